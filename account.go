@@ -0,0 +1,780 @@
+package simutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserExists         = DefineError(ErrCodeUserExists, http.StatusConflict, "user with this {field} already exists")
+	ErrInvalidCredentials = DefineError(ErrCodeInvalidCredentials, http.StatusUnauthorized, "invalid username or password")
+	ErrUserLocked         = DefineError(ErrCodeUserLocked, http.StatusLocked, "user is locked, try again later")
+	ErrUserNotActive      = DefineError(ErrCodeUserNotActive, http.StatusForbidden, "user is not active")
+	ErrInvalidCode        = DefineError(ErrCodeInvalidCode, http.StatusBadRequest, "invalid or expired code")
+	ErrTooManyRequests    = DefineError(ErrCodeTooManyRequests, http.StatusTooManyRequests, "too many requests, try again later")
+)
+
+const (
+	// UserTokenActivation is the purpose of activation codes
+	UserTokenActivation = "activation"
+	// UserTokenReset is the purpose of password reset tokens
+	UserTokenReset = "reset"
+)
+
+type (
+	// UserServiceConfig configures UserService, zero values are replaced by defaults
+	UserServiceConfig struct {
+		// PasswordHash is argon2id or bcrypt, argon2id by default.
+		// Hashes of the other algorithm are verified and rehashed on login.
+		PasswordHash string `json:"password_hash,omitempty"`
+		// MinPasswordLength is 8 by default
+		MinPasswordLength int `json:"min_password_length,omitempty"`
+		// MaxFailedLogins locks the user after failed logins or activations, 5 by default
+		MaxFailedLogins int `json:"max_failed_logins,omitempty"`
+		// LockoutDuration is 15 minutes by default
+		LockoutDuration Duration `json:"lockout_duration,omitempty"`
+		// ActivationCodeLength is the digits of activation codes, 6 by default
+		ActivationCodeLength int `json:"activation_code_length,omitempty"`
+		// ActivationTTL is 24 hours by default
+		ActivationTTL Duration `json:"activation_ttl,omitempty"`
+		// ResendInterval is the least interval between activation codes or reset tokens of a user, 1 minute by default
+		ResendInterval Duration `json:"resend_interval,omitempty"`
+		// ResetTTL is the lifetime of password reset tokens, 1 hour by default
+		ResetTTL Duration `json:"reset_ttl,omitempty"`
+		// DefaultRole is the role of registered users
+		DefaultRole string `json:"default_role,omitempty"`
+	}
+
+	// UserToken is an activation code or password reset token of a user, only its sha256 is stored
+	UserToken struct {
+		ID        PID       `json:"id" gorm:"primaryKey"`
+		UserID    PID       `json:"user_id" gorm:"index"`
+		Purpose   string    `json:"purpose" gorm:"size:16"`
+		Hash      string    `json:"-" gorm:"size:64;index"`
+		ExpiresAt time.Time `json:"expires_at"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// UserNotifier delivers an activation code or password reset token to user, like by sms or email
+	UserNotifier func(ctx context.Context, user *User, purpose, code string) error
+
+	// UserService registers, activates and authenticates users.
+	// The users and user_tokens tables must be migrated by Migrate.
+	UserService struct {
+		// Notify delivers activation codes and reset tokens, they are only returned by the Go API if it is nil
+		Notify UserNotifier
+
+		db     *gorm.DB
+		config UserServiceConfig
+		hasher PasswordHasher
+		auth   *Authenticator
+	}
+)
+
+// NewUserService returns the user service of db, auth issues the tokens of login routes and may be nil
+func NewUserService(db *gorm.DB, config UserServiceConfig, auth *Authenticator) (*UserService, error) {
+	hasher, err := NewPasswordHasher(config.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+
+	config.MinPasswordLength = DefaultIfZero(config.MinPasswordLength, 8)
+	config.MaxFailedLogins = DefaultIfZero(config.MaxFailedLogins, 5)
+	config.LockoutDuration.Duration = DefaultIfZero(config.LockoutDuration.Duration, 15*time.Minute)
+	config.ActivationCodeLength = DefaultIfZero(config.ActivationCodeLength, 6)
+	config.ActivationTTL.Duration = DefaultIfZero(config.ActivationTTL.Duration, 24*time.Hour)
+	config.ResendInterval.Duration = DefaultIfZero(config.ResendInterval.Duration, time.Minute)
+	config.ResetTTL.Duration = DefaultIfZero(config.ResetTTL.Duration, time.Hour)
+
+	s := &UserService{db: db, config: config, hasher: hasher, auth: auth}
+	if auth != nil {
+		auth.SetUserLoader(s.loadUser)
+		auth.SetUserGeneration(s.tokenGeneration)
+	}
+
+	return s, nil
+}
+
+// Migrate migrates the users and user_tokens tables and the unique indexes of
+// the emails and mobiles of users which are not empty
+func (s *UserService) Migrate(ctx context.Context) error {
+	db := s.db.WithContext(ctx)
+	if err := db.AutoMigrate(&User{}, &UserToken{}); err != nil {
+		return err
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&User{}); err != nil {
+		return err
+	}
+
+	for _, column := range []string{"email", "mobile"} {
+		index := "uix_" + stmt.Schema.Table + "_" + column
+		if db.Migrator().HasIndex(&User{}, index) {
+			continue
+		}
+
+		// mysql has no partial indexes but its unique indexes allow several nulls
+		sql := fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s) WHERE %[3]s <> ''", stmt.Quote(index), stmt.Quote(stmt.Schema.Table), stmt.Quote(column))
+		if DriverOf(db) == MySQL {
+			sql = fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s ((NULLIF(%s, '')))", stmt.Quote(index), stmt.Quote(stmt.Schema.Table), stmt.Quote(column))
+		}
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadUser is the UserLoader of refreshed tokens
+func (s *UserService) loadUser(ctx context.Context, userID PID) (*User, string, error) {
+	user := new(User)
+	if err := s.db.WithContext(ctx).First(user, userID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrInvalidToken
+	} else if err != nil {
+		return nil, "", err
+	}
+	if user.Status != USER_STATUS_ACTIVE {
+		return nil, "", ErrUserNotActive
+	}
+	if err := s.checkLock(user); err != nil {
+		return nil, "", err
+	}
+
+	return user, user.Role, nil
+}
+
+// tokenGeneration is the UserGeneration of the authenticator
+func (s *UserService) tokenGeneration(ctx context.Context, userID PID) (int, error) {
+	var gens []int
+	if err := s.db.WithContext(ctx).Model(&User{}).Where("id = ?", userID).Limit(1).Pluck("token_generation", &gens).Error; err != nil {
+		return 0, err
+	}
+	if len(gens) == 0 {
+		return 0, ErrInvalidToken
+	}
+
+	return gens[0], nil
+}
+
+// Register creates a pending user with password and returns its activation code.
+// Username, email and mobile must be unique. The user is registered even if Notify fails.
+func (s *UserService) Register(ctx context.Context, user *User, password string) (code string, err error) {
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+	user.Mobile = sanitizeString(user.Mobile, "trim,digits")
+
+	if err = s.checkPassword(password); err != nil {
+		return
+	}
+	if err = s.checkUnique(ctx, user); err != nil {
+		return
+	}
+	if user.Password, err = s.hasher.Hash(password); err != nil {
+		return
+	}
+
+	user.Status = PENDING
+	user.Role = DefaultIfZero(user.Role, s.config.DefaultRole)
+	user.FailedLogins, user.LockedUntil = 0, nil
+
+	if err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return TranslateGormError(err)
+		}
+
+		code, err = s.issueToken(tx, user.ID, UserTokenActivation)
+		return err
+	}); errors.Is(err, ErrAlreadyExist) {
+		// another user is registered by the same fields after checkUnique
+		if err := s.checkUnique(ctx, user); err != nil {
+			return "", err
+		}
+		return "", err
+	} else if err != nil {
+		return "", err
+	}
+
+	return code, s.notify(ctx, user, UserTokenActivation, code)
+}
+
+// SendActivation issues a new activation code of a pending user, the previous code is invalidated.
+// It returns ErrTooManyRequests if the previous code is issued in ResendInterval.
+func (s *UserService) SendActivation(ctx context.Context, userID PID) (code string, err error) {
+	user, err := s.find(ctx, userID)
+	if err != nil {
+		return
+	}
+	if user.Status != PENDING {
+		return "", ErrInvalidCode
+	}
+
+	if code, err = s.resendToken(ctx, user.ID, UserTokenActivation); err != nil {
+		return
+	}
+
+	return code, s.notify(ctx, user, UserTokenActivation, code)
+}
+
+// Activate activates a pending user by its activation code,
+// wrong codes are counted as failed logins
+func (s *UserService) Activate(ctx context.Context, userID PID, code string) error {
+	user, err := s.find(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkLock(user); err != nil {
+		return err
+	}
+	if user.Status != PENDING {
+		return ErrInvalidCode
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := s.consumeToken(tx, &user.ID, UserTokenActivation, code); err != nil {
+			return err
+		}
+
+		return tx.Model(user).Updates(map[string]any{"status": USER_STATUS_ACTIVE, "failed_logins": 0, "locked_until": nil}).Error
+	})
+	if errors.Is(err, ErrInvalidCode) {
+		if err := s.failed(s.db.WithContext(ctx), user); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// Authenticate returns the active user whose username, email or mobile is login.
+// The user is locked after MaxFailedLogins wrong passwords and its password is rehashed if it is needed.
+func (s *UserService) Authenticate(ctx context.Context, login, password string) (*User, error) {
+	user := new(User)
+	if err := s.db.WithContext(ctx).Where(s.loginQuery(login)).First(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// hash anyway, so missing users are not distinguishable by response time
+			_, _ = s.hasher.Hash(password)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := s.checkLock(user); err != nil {
+		return nil, err
+	}
+
+	ok, rehash, err := s.hasher.Verify(user.Password, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.failed(s.db.WithContext(ctx), user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.Status != USER_STATUS_ACTIVE {
+		return nil, ErrUserNotActive
+	}
+
+	updates := map[string]any{"failed_logins": 0, "locked_until": nil}
+	if rehash {
+		if updates["password"], err = s.hasher.Hash(password); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.db.WithContext(ctx).Model(user).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ChangePassword changes the password of user if its current password is correct and revokes its tokens
+func (s *UserService) ChangePassword(ctx context.Context, userID PID, current, password string) error {
+	user, err := s.find(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkLock(user); err != nil {
+		return err
+	}
+
+	if ok, _, err := s.hasher.Verify(user.Password, current); err != nil {
+		return err
+	} else if !ok {
+		if err := s.failed(s.db.WithContext(ctx), user); err != nil {
+			return err
+		}
+		return ErrInvalidCredentials
+	}
+
+	return s.setPassword(s.db.WithContext(ctx), user, password)
+}
+
+// RequestPasswordReset issues a password reset token of the user whose username, email or mobile is login.
+// It returns ErrTooManyRequests if the previous token is issued in ResendInterval.
+func (s *UserService) RequestPasswordReset(ctx context.Context, login string) (token string, err error) {
+	user := new(User)
+	if err = s.db.WithContext(ctx).Where(s.loginQuery(login)).First(user).Error; err != nil {
+		return "", TranslateGormError(err)
+	}
+
+	if token, err = s.resendToken(ctx, user.ID, UserTokenReset); err != nil {
+		return
+	}
+
+	return token, s.notify(ctx, user, UserTokenReset, token)
+}
+
+// ResetPassword sets the password of the user of a reset token, unlocks it and revokes its tokens
+func (s *UserService) ResetPassword(ctx context.Context, token, password string) error {
+	if err := s.checkPassword(password); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		t, err := s.consumeToken(tx, nil, UserTokenReset, token)
+		if err != nil {
+			return err
+		}
+
+		user := new(User)
+		if err := tx.First(user, t.UserID).Error; err != nil {
+			return TranslateGormError(err)
+		}
+
+		return s.setPassword(tx, user, password)
+	})
+}
+
+// SetStatus changes the status of user, like deactivating it, and revokes its tokens
+func (s *UserService) SetStatus(ctx context.Context, userID PID, status UserMode) error {
+	if status < PENDING || status > USER_STATUS_INACTIVE {
+		return ErrInvalidRequest
+	}
+
+	result := s.db.WithContext(ctx).Model(&User{}).Where("id = ?", userID).
+		Updates(map[string]any{"status": status, "token_generation": gorm.Expr("token_generation + 1")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (s *UserService) find(ctx context.Context, userID PID) (*User, error) {
+	user := new(User)
+	if err := s.db.WithContext(ctx).First(user, userID).Error; err != nil {
+		return nil, TranslateGormError(err)
+	}
+
+	return user, nil
+}
+
+// loginQuery returns the condition of users whose username, email or mobile is login
+func (s *UserService) loginQuery(login string) *gorm.DB {
+	login = sanitizeString(login, "trim,digits")
+
+	return s.db.Where("username = ?", login).
+		Or("email <> '' AND email = ?", strings.ToLower(login)).
+		Or("mobile <> '' AND mobile = ?", login)
+}
+
+func (s *UserService) checkPassword(password string) error {
+	if len([]rune(password)) < s.config.MinPasswordLength {
+		return ValidationErrors{{Field: "password", Message: "password is too short", Rule: "min"}}
+	}
+
+	return nil
+}
+
+// checkUnique returns ErrUserExists if username, email or mobile of user is used by another user,
+// it is checked again by the unique indexes of users
+func (s *UserService) checkUnique(ctx context.Context, user *User) error {
+	for _, f := range []struct{ column, value string }{
+		{"username", user.Username},
+		{"email", user.Email},
+		{"mobile", user.Mobile},
+	} {
+		if f.value == "" {
+			continue
+		}
+
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&User{}).Where(f.column+" = ? AND id <> ?", f.value, user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserExists.WithDetails(map[string]any{"field": f.column})
+		}
+	}
+
+	return nil
+}
+
+func (s *UserService) checkLock(user *User) error {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return ErrUserLocked
+	}
+
+	return nil
+}
+
+// failed counts a failed attempt of user and locks it after MaxFailedLogins attempts
+func (s *UserService) failed(db *gorm.DB, user *User) error {
+	if err := db.Model(user).UpdateColumn("failed_logins", gorm.Expr("failed_logins + 1")).Error; err != nil {
+		return err
+	}
+	if err := db.Model(user).Select("failed_logins").First(user).Error; err != nil {
+		return err
+	}
+
+	if user.FailedLogins < s.config.MaxFailedLogins {
+		return nil
+	}
+
+	until := time.Now().Add(s.config.LockoutDuration.Duration)
+	user.FailedLogins, user.LockedUntil = 0, &until
+
+	return db.Model(user).UpdateColumns(map[string]any{"failed_logins": 0, "locked_until": until}).Error
+}
+
+func (s *UserService) setPassword(db *gorm.DB, user *User, password string) error {
+	if err := s.checkPassword(password); err != nil {
+		return err
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Where("user_id = ? AND purpose = ?", user.ID, UserTokenReset).Delete(&UserToken{}).Error; err != nil {
+		return err
+	}
+
+	return db.Model(user).Updates(map[string]any{
+		"password":         hash,
+		"failed_logins":    0,
+		"locked_until":     nil,
+		"token_generation": gorm.Expr("token_generation + 1"),
+	}).Error
+}
+
+// issueToken replaces the token of user for purpose with a new one
+func (s *UserService) issueToken(db *gorm.DB, userID PID, purpose string) (code string, err error) {
+	ttl := s.config.ResetTTL.Duration
+	if purpose == UserTokenActivation {
+		code, ttl = GenerateVerificationCode(s.config.ActivationCodeLength), s.config.ActivationTTL.Duration
+	} else if code, err = RandStringCode(32); err != nil {
+		return
+	}
+
+	if err = db.Where("user_id = ? AND purpose = ?", userID, purpose).Delete(&UserToken{}).Error; err != nil {
+		return "", err
+	}
+
+	err = db.Create(&UserToken{UserID: userID, Purpose: purpose, Hash: hashUserToken(code), ExpiresAt: time.Now().Add(ttl)}).Error
+
+	return
+}
+
+// resendToken issues a token like issueToken unless the previous token of purpose is issued in ResendInterval
+func (s *UserService) resendToken(ctx context.Context, userID PID, purpose string) (code string, err error) {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var sent int64
+		if err := tx.Model(&UserToken{}).
+			Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, time.Now().Add(-s.config.ResendInterval.Duration)).
+			Count(&sent).Error; err != nil {
+			return err
+		}
+		if sent > 0 {
+			return ErrTooManyRequests
+		}
+
+		code, err = s.issueToken(tx, userID, purpose)
+		return err
+	})
+
+	return
+}
+
+// consumeToken deletes and returns the token of code which is not expired, userID limits the owner of token if it is given
+func (s *UserService) consumeToken(db *gorm.DB, userID *PID, purpose, code string) (*UserToken, error) {
+	q := db.Where("purpose = ? AND hash = ? AND expires_at > ?", purpose, hashUserToken(code), time.Now())
+	if userID != nil {
+		q = q.Where("user_id = ?", *userID)
+	}
+
+	t := new(UserToken)
+	if err := q.First(t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCode
+		}
+		return nil, err
+	}
+
+	if err := db.Delete(t).Error; err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (s *UserService) notify(ctx context.Context, user *User, purpose, code string) error {
+	if s.Notify == nil {
+		return nil
+	}
+
+	return s.Notify(ctx, user, purpose, code)
+}
+
+func hashUserToken(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+type (
+	registerUserRequest struct {
+		Username  string `json:"username" sanitize:"trim" valid:"required"`
+		Email     string `json:"email" sanitize:"trim,lower" valid:"email"`
+		Mobile    string `json:"mobile" sanitize:"trim,digits" valid:"numeric"`
+		Firstname string `json:"firstname" sanitize:"trim,fa"`
+		Lastname  string `json:"lastname" sanitize:"trim,fa"`
+		Password  string `json:"password" valid:"required"`
+	}
+
+	activateUserRequest struct {
+		UserID PID    `json:"user_id" valid:"required"`
+		Code   string `json:"code" sanitize:"trim,digits" valid:"required"`
+	}
+
+	loginUserRequest struct {
+		Login    string `json:"login" sanitize:"trim,digits" valid:"required"`
+		Password string `json:"password" valid:"required"`
+	}
+
+	resetPasswordRequest struct {
+		Token    string `json:"token" sanitize:"trim" valid:"required"`
+		Password string `json:"password" valid:"required"`
+	}
+
+	changePasswordRequest struct {
+		CurrentPassword string `json:"current_password" valid:"required"`
+		Password        string `json:"password" valid:"required"`
+	}
+
+	refreshTokenRequest struct {
+		RefreshToken string `json:"refresh_token" sanitize:"trim"`
+	}
+)
+
+// Routes mounts the routes of the user service on g:
+//
+//	POST /register            registers a pending user
+//	POST /activate            activates a user by its code
+//	POST /activate/resend     sends a new activation code
+//	POST /login               returns the tokens of user, or the user if there is no authenticator
+//	POST /password/forgot     sends a password reset token
+//	POST /password/reset      resets the password by a reset token
+//	PUT  /password            changes the password of the current user and revokes its tokens
+//	POST /refresh             returns new tokens of a refresh token
+//	POST /logout              revokes the access token and the refresh token
+//
+// The refresh and logout routes are mounted only if the service has an authenticator.
+func (s *UserService) Routes(g *echo.Group) {
+	var authenticated []echo.MiddlewareFunc
+	if s.auth != nil {
+		authenticated = append(authenticated, s.auth.Middleware())
+	}
+
+	g.POST("/register", s.registerHandler)
+	g.POST("/activate", s.activateHandler)
+	g.POST("/activate/resend", s.resendActivationHandler)
+	g.POST("/login", s.loginHandler)
+	g.POST("/password/forgot", s.forgotPasswordHandler)
+	g.POST("/password/reset", s.resetPasswordHandler)
+	g.PUT("/password", s.changePasswordHandler, authenticated...)
+
+	if s.auth != nil {
+		g.POST("/refresh", s.refreshHandler)
+		g.POST("/logout", s.logoutHandler, authenticated...)
+	}
+}
+
+func (s *UserService) registerHandler(ctx echo.Context) error {
+	req, err := Bind[registerUserRequest](ctx)
+	if err != nil {
+		return err
+	}
+
+	user := &User{
+		Username:  req.Username,
+		Email:     req.Email,
+		Mobile:    req.Mobile,
+		Firstname: req.Firstname,
+		Lastname:  req.Lastname,
+	}
+	if _, err := s.Register(ctx.Request().Context(), user, req.Password); err != nil {
+		return err
+	}
+
+	return Created(ctx, user)
+}
+
+func (s *UserService) activateHandler(ctx echo.Context) error {
+	req, err := Bind[activateUserRequest](ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Activate(ctx.Request().Context(), req.UserID, req.Code); err != nil {
+		return err
+	}
+
+	return OK[any](ctx, nil, nil)
+}
+
+func (s *UserService) resendActivationHandler(ctx echo.Context) error {
+	req, err := Bind[struct {
+		UserID PID `json:"user_id" valid:"required"`
+	}](ctx)
+	if err != nil {
+		return err
+	}
+
+	// every request is accepted, so users are not discoverable by this route
+	if _, err := s.SendActivation(ctx.Request().Context(), req.UserID); err != nil {
+		acceptedError(ctx, "resend activation", err)
+	}
+
+	return Respond[any](ctx, http.StatusAccepted, nil, nil, nil)
+}
+
+func (s *UserService) loginHandler(ctx echo.Context) error {
+	req, err := Bind[loginUserRequest](ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.Authenticate(ctx.Request().Context(), req.Login, req.Password)
+	if err != nil {
+		return err
+	}
+
+	if s.auth == nil {
+		return OK(ctx, user, nil)
+	}
+
+	pair, err := s.auth.Issue(user, user.Role, ctx.Request().Header.Get(HeadersClient))
+	if err != nil {
+		return err
+	}
+
+	return OK(ctx, pair, nil)
+}
+
+func (s *UserService) forgotPasswordHandler(ctx echo.Context) error {
+	req, err := Bind[struct {
+		Login string `json:"login" sanitize:"trim,digits" valid:"required"`
+	}](ctx)
+	if err != nil {
+		return err
+	}
+
+	// every request is accepted, so users are not discoverable by this route
+	if _, err := s.RequestPasswordReset(ctx.Request().Context(), req.Login); err != nil {
+		acceptedError(ctx, "forgot password", err)
+	}
+
+	return Respond[any](ctx, http.StatusAccepted, nil, nil, nil)
+}
+
+// acceptedError logs the unexpected errors of the routes which always respond 202,
+// like the failures of database or Notify
+func acceptedError(ctx echo.Context, route string, err error) {
+	if errors.Is(err, ErrRecordNotFound) || errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrTooManyRequests) {
+		return
+	}
+
+	ContextOf(ctx).Log().WithError(err).Errorln(route, "failed")
+}
+
+func (s *UserService) resetPasswordHandler(ctx echo.Context) error {
+	req, err := Bind[resetPasswordRequest](ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ResetPassword(ctx.Request().Context(), req.Token, req.Password); err != nil {
+		return err
+	}
+
+	return OK[any](ctx, nil, nil)
+}
+
+func (s *UserService) changePasswordHandler(ctx echo.Context) error {
+	user, ok := ContextOf(ctx).CurrentUser()
+	if !ok {
+		return ErrUnauthorized
+	}
+
+	req, err := Bind[changePasswordRequest](ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ChangePassword(ctx.Request().Context(), user.ID, req.CurrentPassword, req.Password); err != nil {
+		return err
+	}
+
+	return OK[any](ctx, nil, nil)
+}
+
+func (s *UserService) refreshHandler(ctx echo.Context) error {
+	req, err := Bind[refreshTokenRequest](ctx)
+	if err != nil {
+		return err
+	}
+
+	pair, err := s.auth.Refresh(ctx.Request().Context(), req.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	return OK(ctx, pair, nil)
+}
+
+func (s *UserService) logoutHandler(ctx echo.Context) error {
+	req, err := Bind[refreshTokenRequest](ctx)
+	if err != nil {
+		return err
+	}
+
+	if token, ok := bearerToken(ctx.Request()); ok {
+		if err := s.auth.Revoke(ctx.Request().Context(), token); err != nil {
+			return err
+		}
+	}
+	if req.RefreshToken != "" {
+		if err := s.auth.Revoke(ctx.Request().Context(), req.RefreshToken); err != nil {
+			return err
+		}
+	}
+
+	return OK[any](ctx, nil, nil)
+}
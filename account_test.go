@@ -0,0 +1,369 @@
+package simutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func newAccountTestService(t *testing.T, name string, auth *Authenticator) (*UserService, *gorm.DB) {
+	t.Helper()
+
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:" + name + "?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewUserService(dbConn.DB, UserServiceConfig{MaxFailedLogins: 3, DefaultRole: "customer"}, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return s, dbConn.DB
+}
+
+func TestUserService(t *testing.T) {
+	s, db := newAccountTestService(t, "account_test", nil)
+	ctx := context.Background()
+
+	var notified []string
+	s.Notify = func(_ context.Context, user *User, purpose, code string) error {
+		notified = append(notified, purpose+":"+code)
+		return nil
+	}
+
+	user := &User{Username: "ali", Email: " Ali@Example.com ", Mobile: "۰۹۱۲۰۰۰۰۰۰۰"}
+	code, err := s.Register(ctx, user, "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Status != PENDING || user.Role != "customer" || user.Email != "ali@example.com" || user.Mobile != "09120000000" {
+		t.Errorf("Register() user = %+v", user)
+	}
+	if strings.HasPrefix(user.Password, "password1") || len(notified) != 1 || notified[0] != "activation:"+code {
+		t.Errorf("Register() password = %s, notified = %v", user.Password, notified)
+	}
+
+	t.Run("unique", func(t *testing.T) {
+		_, err := s.Register(ctx, &User{Username: "other", Mobile: "09120000000"}, "password1")
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.Code != ErrCodeUserExists || appErr.Details["field"] != "mobile" {
+			t.Errorf("Register() error = %v, want %v of mobile", err, ErrUserExists)
+		}
+	})
+
+	t.Run("unique index", func(t *testing.T) {
+		if err := db.Create(&User{Username: "other", Email: "ali@example.com"}).Error; err == nil {
+			t.Error("Create(duplicate email) error = nil")
+		}
+		if err := db.Create(&User{Username: "empty1"}).Error; err != nil {
+			t.Errorf("Create(empty email) error = %v", err)
+		}
+		if err := db.Create(&User{Username: "empty2"}).Error; err != nil {
+			t.Errorf("Create(empty email) error = %v", err)
+		}
+		if err := s.Migrate(ctx); err != nil {
+			t.Errorf("Migrate() again error = %v", err)
+		}
+	})
+
+	t.Run("short password", func(t *testing.T) {
+		var errs ValidationErrors
+		if _, err := s.Register(ctx, &User{Username: "short"}, "123"); !errors.As(err, &errs) || errs[0].Field != "password" {
+			t.Errorf("Register() error = %v, want password validation error", err)
+		}
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		if _, err := s.Authenticate(ctx, "ali", "password1"); !errors.Is(err, ErrUserNotActive) {
+			t.Errorf("Authenticate() error = %v, want %v", err, ErrUserNotActive)
+		}
+	})
+
+	t.Run("resend", func(t *testing.T) {
+		if _, err := s.SendActivation(ctx, user.ID); !errors.Is(err, ErrTooManyRequests) {
+			t.Errorf("SendActivation() in interval error = %v, want %v", err, ErrTooManyRequests)
+		}
+		if err := db.Model(&UserToken{}).Where("user_id = ?", user.ID).Update("created_at", time.Now().Add(-2*time.Minute)).Error; err != nil {
+			t.Fatal(err)
+		}
+		if code, err = s.SendActivation(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if len(notified) != 2 || notified[1] != "activation:"+code {
+			t.Errorf("SendActivation() notified = %v", notified)
+		}
+	})
+
+	t.Run("activate", func(t *testing.T) {
+		if err := s.Activate(ctx, user.ID, "0"); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("Activate(wrong code) error = %v, want %v", err, ErrInvalidCode)
+		}
+		if err := s.Activate(ctx, user.ID, code); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Activate(ctx, user.ID, code); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("Activate(used code) error = %v, want %v", err, ErrInvalidCode)
+		}
+	})
+
+	t.Run("authenticate", func(t *testing.T) {
+		for _, login := range []string{"ali", "ALI@example.com", "۰۹۱۲۰۰۰۰۰۰۰"} {
+			got, err := s.Authenticate(ctx, login, "password1")
+			if err != nil {
+				t.Fatalf("Authenticate(%s) error = %v", login, err)
+			}
+			if got.ID != user.ID || got.FailedLogins != 0 {
+				t.Errorf("Authenticate(%s) = %+v", login, got)
+			}
+		}
+
+		if _, err := s.Authenticate(ctx, "nobody", "password1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Authenticate(unknown user) error = %v, want %v", err, ErrInvalidCredentials)
+		}
+	})
+
+	t.Run("lockout", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := s.Authenticate(ctx, "ali", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("Authenticate(wrong password) error = %v, want %v", err, ErrInvalidCredentials)
+			}
+		}
+		if _, err := s.Authenticate(ctx, "ali", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Authenticate(wrong password) error = %v, want %v", err, ErrInvalidCredentials)
+		}
+		if _, err := s.Authenticate(ctx, "ali", "password1"); !errors.Is(err, ErrUserLocked) {
+			t.Errorf("Authenticate(locked user) error = %v, want %v", err, ErrUserLocked)
+		}
+	})
+
+	t.Run("reset password", func(t *testing.T) {
+		token, err := s.RequestPasswordReset(ctx, "ali@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.RequestPasswordReset(ctx, "ali"); !errors.Is(err, ErrTooManyRequests) {
+			t.Errorf("RequestPasswordReset() in interval error = %v, want %v", err, ErrTooManyRequests)
+		}
+		if err := s.ResetPassword(ctx, "wrong", "password2"); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("ResetPassword(wrong token) error = %v, want %v", err, ErrInvalidCode)
+		}
+		if err := s.ResetPassword(ctx, token, "password2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.ResetPassword(ctx, token, "password3"); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("ResetPassword(used token) error = %v, want %v", err, ErrInvalidCode)
+		}
+		if _, err := s.Authenticate(ctx, "ali", "password2"); err != nil {
+			t.Errorf("Authenticate(reset password) error = %v", err)
+		}
+	})
+
+	t.Run("change password", func(t *testing.T) {
+		if err := s.ChangePassword(ctx, user.ID, "wrong", "password3"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("ChangePassword(wrong password) error = %v, want %v", err, ErrInvalidCredentials)
+		}
+		if err := s.ChangePassword(ctx, user.ID, "password2", "password3"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Authenticate(ctx, "ali", "password3"); err != nil {
+			t.Errorf("Authenticate(changed password) error = %v", err)
+		}
+	})
+
+	t.Run("rehash", func(t *testing.T) {
+		hash, err := (&BcryptHasher{Cost: bcrypt.MinCost}).Hash("password3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(user).Update("password", hash).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.Authenticate(ctx, "ali", "password3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(got.Password, "$argon2id$") {
+			t.Errorf("password is not rehashed: %s", got.Password)
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		if err := s.SetStatus(ctx, user.ID, USER_STATUS_INACTIVE); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Authenticate(ctx, "ali", "password3"); !errors.Is(err, ErrUserNotActive) {
+			t.Errorf("Authenticate(inactive user) error = %v, want %v", err, ErrUserNotActive)
+		}
+		if err := s.SetStatus(ctx, 1000, USER_STATUS_ACTIVE); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("SetStatus(unknown user) error = %v, want %v", err, ErrRecordNotFound)
+		}
+	})
+}
+
+func TestUserService_Routes(t *testing.T) {
+	auth, err := NewAuthenticator(AuthConfig{Keys: []AuthKey{{ID: "k1", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, db := newAccountTestService(t, "account_routes_test", auth)
+	codes := map[string]string{}
+	s.Notify = func(_ context.Context, user *User, purpose, code string) error {
+		codes[purpose] = code
+		return nil
+	}
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler(false)
+	e.Use(ContextMiddleware())
+	s.Routes(e.Group("/users"))
+
+	call := func(method, path, token string, body any) (int, map[string]any) {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, strings.NewReader(string(b)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var resp map[string]any
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+
+		return rec.Code, resp
+	}
+
+	status, resp := call(http.MethodPost, "/users/register", "", map[string]any{"username": "u", "email": "u@example.com", "password": "password1"})
+	if status != http.StatusCreated {
+		t.Fatalf("register status = %d: %v", status, resp)
+	}
+	data, _ := resp["data"].(map[string]any)
+	if _, ok := data["password"]; ok {
+		t.Errorf("register responds password: %v", data)
+	}
+
+	if status, resp := call(http.MethodPost, "/users/register", "", map[string]any{"username": "u2", "email": "bad"}); status != http.StatusUnprocessableEntity {
+		t.Errorf("register invalid status = %d: %v", status, resp)
+	}
+
+	for _, id := range []any{data["id"], 1000} {
+		if status, resp := call(http.MethodPost, "/users/activate/resend", "", map[string]any{"user_id": id}); status != http.StatusAccepted {
+			t.Errorf("resend activation of %v status = %d: %v", id, status, resp)
+		}
+	}
+
+	if status, resp := call(http.MethodPost, "/users/activate", "", map[string]any{"user_id": data["id"], "code": codes[UserTokenActivation]}); status != http.StatusOK {
+		t.Fatalf("activate status = %d: %v", status, resp)
+	}
+
+	status, resp = call(http.MethodPost, "/users/login", "", map[string]any{"login": "u", "password": "password1"})
+	if status != http.StatusOK {
+		t.Fatalf("login status = %d: %v", status, resp)
+	}
+	data, _ = resp["data"].(map[string]any)
+	access, _ := data["access_token"].(string)
+	refresh, _ := data["refresh_token"].(string)
+
+	if status, resp := call(http.MethodPost, "/users/login", "", map[string]any{"login": "u", "password": "wrong"}); status != http.StatusUnauthorized || resp["error_code"] != string(ErrCodeInvalidCredentials) {
+		t.Errorf("login wrong password status = %d: %v", status, resp)
+	}
+
+	if status, resp := call(http.MethodPut, "/users/password", "", map[string]any{"current_password": "password1", "password": "password2"}); status != http.StatusUnauthorized {
+		t.Errorf("change password without token status = %d: %v", status, resp)
+	}
+	if status, resp := call(http.MethodPut, "/users/password", access, map[string]any{"current_password": "password1", "password": "password2"}); status != http.StatusOK {
+		t.Errorf("change password status = %d: %v", status, resp)
+	}
+
+	if status, resp := call(http.MethodPost, "/users/password/forgot", "", map[string]any{"login": "nobody"}); status != http.StatusAccepted {
+		t.Errorf("forgot password of unknown user status = %d: %v", status, resp)
+	}
+	if status, resp := call(http.MethodPost, "/users/password/forgot", "", map[string]any{"login": "u@example.com"}); status != http.StatusAccepted {
+		t.Errorf("forgot password status = %d: %v", status, resp)
+	}
+	reset := codes[UserTokenReset]
+	if status, resp := call(http.MethodPost, "/users/password/forgot", "", map[string]any{"login": "u@example.com"}); status != http.StatusAccepted || codes[UserTokenReset] != reset {
+		t.Errorf("forgot password in interval status = %d: %v", status, resp)
+	}
+	if status, resp := call(http.MethodPost, "/users/password/reset", "", map[string]any{"token": codes[UserTokenReset], "password": "password3"}); status != http.StatusOK {
+		t.Errorf("reset password status = %d: %v", status, resp)
+	}
+
+	// the password change and the reset revoke the tokens of login
+	if status, resp := call(http.MethodPost, "/users/refresh", "", map[string]any{"refresh_token": refresh}); status != http.StatusUnauthorized || resp["error_code"] != string(ErrCodeTokenRevoked) {
+		t.Errorf("refresh after password reset status = %d: %v", status, resp)
+	}
+	if status, resp := call(http.MethodPut, "/users/password", access, map[string]any{"current_password": "password3", "password": "password4"}); status != http.StatusUnauthorized {
+		t.Errorf("change password by revoked token status = %d: %v", status, resp)
+	}
+
+	status, resp = call(http.MethodPost, "/users/login", "", map[string]any{"login": "u", "password": "password3"})
+	if status != http.StatusOK {
+		t.Fatalf("login status = %d: %v", status, resp)
+	}
+	data, _ = resp["data"].(map[string]any)
+	refresh, _ = data["refresh_token"].(string)
+
+	status, resp = call(http.MethodPost, "/users/refresh", "", map[string]any{"refresh_token": refresh})
+	if status != http.StatusOK {
+		t.Fatalf("refresh status = %d: %v", status, resp)
+	}
+	data, _ = resp["data"].(map[string]any)
+	access, _ = data["access_token"].(string)
+	refresh, _ = data["refresh_token"].(string)
+
+	if status, resp := call(http.MethodPost, "/users/logout", access, map[string]any{"refresh_token": refresh}); status != http.StatusOK {
+		t.Errorf("logout status = %d: %v", status, resp)
+	}
+	if status, resp := call(http.MethodPost, "/users/refresh", "", map[string]any{"refresh_token": refresh}); status != http.StatusUnauthorized {
+		t.Errorf("refresh after logout status = %d: %v", status, resp)
+	}
+
+	status, resp = call(http.MethodPost, "/users/login", "", map[string]any{"login": "u", "password": "password3"})
+	if status != http.StatusOK {
+		t.Fatalf("login status = %d: %v", status, resp)
+	}
+	data, _ = resp["data"].(map[string]any)
+	access, _ = data["access_token"].(string)
+	refresh, _ = data["refresh_token"].(string)
+
+	// a user which is deactivated without revoking its tokens can not refresh them
+	if err := db.Model(&User{}).Where("username = ?", "u").Update("status", USER_STATUS_INACTIVE).Error; err != nil {
+		t.Fatal(err)
+	}
+	if status, resp := call(http.MethodPost, "/users/refresh", "", map[string]any{"refresh_token": refresh}); status != http.StatusForbidden || resp["error_code"] != string(ErrCodeUserNotActive) {
+		t.Errorf("refresh of inactive user status = %d: %v", status, resp)
+	}
+
+	user := new(User)
+	if err := db.Where("username = ?", "u").First(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetStatus(context.Background(), user.ID, USER_STATUS_INACTIVE); err != nil {
+		t.Fatal(err)
+	}
+	if status, resp := call(http.MethodPost, "/users/logout", access, map[string]any{"refresh_token": refresh}); status != http.StatusUnauthorized || resp["error_code"] != string(ErrCodeTokenRevoked) {
+		t.Errorf("logout after status change status = %d: %v", status, resp)
+	}
+
+	// failures of Notify only happen for existing users, so they are not responded
+	s.Notify = func(context.Context, *User, string, string) error { return errors.New("sms is down") }
+	if status, resp := call(http.MethodPost, "/users/password/forgot", "", map[string]any{"login": "u"}); status != http.StatusAccepted {
+		t.Errorf("forgot password with notify failure status = %d: %v", status, resp)
+	}
+}
@@ -0,0 +1,260 @@
+package simutils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// aggRegex defines the regex pattern of aggregate functions like count(*) or sum(price)
+	aggRegex = regexp.MustCompile(`^(count|sum|avg|min|max)\((\*|\w+)\)$`)
+
+	// aggHavingOperators are the operators allowed in `having` query param
+	aggHavingOperators = []string{"eq", "neq", "gt", "gte", "lt", "lte"}
+)
+
+// AggregateFunc is an aggregate function of `agg` query param
+type AggregateFunc struct {
+	// Func is one of count, sum, avg, min and max
+	Func string
+	// Column is the aggregated column, empty for count(*)
+	Column string
+	// Alias is the name of result column, like count or sum_price
+	Alias string
+}
+
+// Aggregation is a group by query parsed from `group_by`, `agg` and `having` query params like
+// `group_by=status&agg=count(*),sum(price)&having=sum_price:gt:100`.
+// Columns and aggregated fields are whitelisted against the filter columns of the list.
+type Aggregation struct {
+	GroupBy []string
+	Funcs   []AggregateFunc
+
+	having []clause.Expr
+}
+
+// ParseAggregation parses the comma separated values of `group_by`, `agg` and `having` query params.
+// Only the keys of columns which map to a single column of model are allowed,
+// if columns is nil the fields of model which are not hidden from json are allowed.
+func ParseAggregation(db *gorm.DB, model any, columns map[string][]string, groupBy, aggs, having []string) (*Aggregation, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	column := func(name string) (string, error) {
+		var f *schema.Field
+		if columns == nil {
+			if f = lookUpSchemaField(stmt.Schema, name); f != nil && f.StructField.Tag.Get("json") == "-" {
+				f = nil
+			}
+		} else if cols := columns[name]; len(cols) == 1 {
+			col := cols[0]
+			if i := strings.LastIndex(col, "."); i >= 0 {
+				col = col[i+1:]
+			}
+			f = stmt.Schema.LookUpField(col)
+		}
+
+		if f == nil || f.DBName == "" || !f.Readable {
+			return "", ErrInvalidRequest
+		}
+		return f.DBName, nil
+	}
+
+	agg := &Aggregation{}
+
+	for _, name := range splitQueryValues(groupBy) {
+		col, err := column(name)
+		if err != nil {
+			return nil, err
+		}
+		agg.GroupBy = append(agg.GroupBy, col)
+	}
+
+	for _, v := range splitQueryValues(aggs) {
+		matches := aggRegex.FindStringSubmatch(strings.ToLower(v))
+		if matches == nil || (matches[2] == "*" && matches[1] != "count") {
+			return nil, ErrInvalidRequest
+		}
+
+		fn := AggregateFunc{Func: matches[1], Alias: matches[1]}
+		if matches[2] != "*" {
+			col, err := column(matches[2])
+			if err != nil {
+				return nil, err
+			}
+			fn.Column = col
+			fn.Alias = matches[1] + "_" + col
+		}
+
+		agg.Funcs = append(agg.Funcs, fn)
+	}
+
+	if len(agg.Funcs) == 0 {
+		agg.Funcs = []AggregateFunc{{Func: "count", Alias: "count"}}
+	}
+
+	for _, v := range splitQueryValues(having) {
+		// alias:operator:value
+		parts := strings.SplitN(v, ":", 3)
+		if len(parts) != 3 || !ArrayElementExists(aggHavingOperators, parts[1]) {
+			return nil, ErrInvalidRequest
+		}
+
+		fn, ok := agg.funcOf(parts[0])
+		if !ok {
+			return nil, ErrInvalidRequest
+		}
+
+		value, err := cast.ToFloat64E(parts[2])
+		if err != nil {
+			return nil, ErrInvalidRequest
+		}
+
+		agg.having = append(agg.having, clause.Expr{
+			SQL:  fmt.Sprintf("%s %s ?", fn.sql(stmt.Quote), mapURLToDBOperator[parts[1]]),
+			Vars: []any{value},
+		})
+	}
+
+	return agg, nil
+}
+
+// Apply selects the group by columns and the aggregates and groups the query
+func (agg *Aggregation) Apply(db *gorm.DB) *gorm.DB {
+	var (
+		quote   = db.Statement.Quote
+		selects []string
+	)
+
+	for _, col := range agg.GroupBy {
+		selects = append(selects, quote(col))
+	}
+	for _, fn := range agg.Funcs {
+		selects = append(selects, fmt.Sprintf("%s AS %s", fn.sql(quote), quote(fn.Alias)))
+	}
+
+	db = db.Select(strings.Join(selects, ", "))
+	for _, col := range agg.GroupBy {
+		db = db.Group(quote(col))
+	}
+	for _, expr := range agg.having {
+		db = db.Having(expr)
+	}
+
+	return db
+}
+
+// Sort orders the query by the group by columns and aggregate aliases of sorts,
+// other keys are ignored
+func (agg *Aggregation) Sort(db *gorm.DB, sorts []SortValue) *gorm.DB {
+	for _, sv := range sorts {
+		if _, ok := agg.funcOf(sv.Key); ok || ArrayElementExists(agg.GroupBy, sv.Key) {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: sv.Key}, Desc: sv.Order == "desc"})
+		}
+	}
+
+	return db
+}
+
+func (agg *Aggregation) funcOf(alias string) (AggregateFunc, bool) {
+	for _, fn := range agg.Funcs {
+		if fn.Alias == alias {
+			return fn, true
+		}
+	}
+
+	return AggregateFunc{}, false
+}
+
+// sql returns the sql expression of aggregate function
+func (fn AggregateFunc) sql(quote func(any) string) string {
+	if fn.Column == "" {
+		return "COUNT(*)"
+	}
+
+	return fmt.Sprintf("%s(%s)", strings.ToUpper(fn.Func), quote(fn.Column))
+}
+
+// Aggregate runs a grouped query on T using the `group_by`, `agg` and `having` query params
+// with the filters, sorts, limit and offset of the request.
+// Rows are returned as maps in a ResponseTemplate paginated by the number of groups.
+func Aggregate[T any](ctx echo.Context, db *gorm.DB, spec ListSpec) (*ResponseTemplate, error) {
+	if ctx.Get(CTXFilters) == nil {
+		if err := ParseURL(ctx); err != nil {
+			return nil, ErrInvalidRequest
+		}
+	}
+
+	limit, offset, filters, sorts := ParseContext(ctx)
+	if maxLimit := DefaultIfZero(spec.MaxLimit, 100); limit > maxLimit {
+		limit = maxLimit
+	}
+
+	params := ctx.QueryParams()
+	agg, err := ParseAggregation(db, new(T), spec.Columns, params["group_by"], params["agg"], params["having"])
+	if err != nil {
+		return nil, err
+	}
+
+	driver := spec.Driver
+	if driver == 0 {
+		driver = DriverOf(db)
+	}
+
+	base := db.WithContext(ctx.Request().Context()).Model(new(T)).Scopes(spec.Scopes...)
+	if base, err = ParseFilters(base, driver, filters, spec.Columns); err != nil {
+		return nil, err
+	}
+	base = agg.Apply(base).Session(&gorm.Session{})
+
+	var (
+		total int64
+		rows  = []map[string]any{}
+	)
+
+	if err = db.WithContext(ctx.Request().Context()).Table("(?) AS aggregate_groups", base).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if err = agg.Sort(base, sorts).Limit(limit).Offset(offset).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	paginate := CreatePaginateTemplate(int(total), offset, limit)
+	paginate.Count = len(rows)
+
+	return ResponseOk(rows, nil, paginate), nil
+}
+
+// aggregationOf parses the aggregation params of BuildGormQuery on the fields of its model
+// which are not hidden from json, nil is returned if there is none
+func aggregationOf(db *gorm.DB, queryParams url.Values) (*Aggregation, error) {
+	if db.Statement.Model == nil || (queryParams.Get("group_by") == "" && queryParams.Get("agg") == "") {
+		return nil, nil
+	}
+
+	return ParseAggregation(db, db.Statement.Model, nil, queryParams["group_by"], queryParams["agg"], queryParams["having"])
+}
+
+// splitQueryValues splits comma separated query values and drops the empty ones
+func splitQueryValues(values []string) (result []string) {
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				result = append(result, s)
+			}
+		}
+	}
+
+	return result
+}
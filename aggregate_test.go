@@ -0,0 +1,103 @@
+package simutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:aggregate_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+
+	if err := db.AutoMigrate(&listTestOwner{}, &listTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 6; i++ {
+		name := "pen"
+		if i > 4 {
+			name = "book"
+		}
+		db.Create(&listTestItem{Model: Model{ID: PID(i)}, Name: name, Price: i * 10, OwnerID: PID(i % 2)})
+	}
+
+	spec := ListSpec{Columns: map[string][]string{"name": {"name"}, "owner_id": {"list_test_items.owner_id"}, "price": {"price"}}}
+
+	tests := []struct {
+		name      string
+		query     string
+		want      []map[string]any
+		wantTotal int
+		wantErr   bool
+	}{
+		{
+			name:      "count and sum",
+			query:     "group_by=name&agg=count(*),sum(price)&sort=sum_price:desc",
+			want:      []map[string]any{{"name": "book", "count": int64(2), "sum_price": int64(110)}, {"name": "pen", "count": int64(4), "sum_price": int64(100)}},
+			wantTotal: 2,
+		},
+		{
+			name:      "having and filter",
+			query:     "group_by=name,owner_id&agg=max(price)&having=max_price:gte:40&price=gt:10&sort=max_price",
+			want:      []map[string]any{{"name": "pen", "owner_id": int64(0), "max_price": int64(40)}, {"name": "book", "owner_id": int64(1), "max_price": int64(50)}, {"name": "book", "owner_id": int64(0), "max_price": int64(60)}},
+			wantTotal: 3,
+		},
+		{
+			name:      "paginated groups",
+			query:     "group_by=owner_id&limit=1&sort=owner_id",
+			want:      []map[string]any{{"owner_id": int64(0), "count": int64(3)}},
+			wantTotal: 2,
+		},
+		{name: "unknown column", query: "group_by=secret", wantErr: true},
+		{name: "column not in spec", query: "group_by=id", wantErr: true},
+		{name: "aggregate of column not in spec", query: "group_by=name&agg=max(id)", wantErr: true},
+		{name: "sum of all columns", query: "agg=sum(*)", wantErr: true},
+		{name: "having unknown alias", query: "agg=count(*)&having=sum_price:gt:1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Aggregate[listTestItem](newListTestContext(tt.query), db, spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Aggregate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if rows := got.Data.([]map[string]any); !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("Aggregate() data = %v, want %v", rows, tt.want)
+			}
+			if paginate := got.Meta.(*PaginateTemplate); paginate.Total != tt.wantTotal {
+				t.Errorf("Aggregate() total = %d, want %d", paginate.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestParseAggregation_HiddenColumns(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:aggregate_hidden_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		groupBy string
+		agg     string
+		wantErr bool
+	}{
+		{name: "visible column", groupBy: "username"},
+		{name: "hidden column", groupBy: "password", wantErr: true},
+		{name: "aggregate of hidden column", groupBy: "username", agg: "max(failed_logins)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAggregation(dbConn.DB, &User{}, nil, []string{tt.groupBy}, []string{tt.agg}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAggregation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
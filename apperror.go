@@ -0,0 +1,140 @@
+package simutils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AppError is an error with a stable code, http status and a message key which is translated
+// by the catalogs of Accept-Language, see RegisterCatalog.
+// Errors are defined once by DefineError and returned with details or cause:
+//
+//	var ErrOrderClosed = simutils.DefineError("order_closed", http.StatusConflict, "order {id} is closed")
+//
+//	return ErrOrderClosed.WithDetails(map[string]any{"id": order.ID})
+//
+// errors.Is matches errors with the same code.
+type AppError struct {
+	Code   ErrorCode `json:"code"`
+	Status int       `json:"status"`
+	// Key is the message key of catalogs, it is the code by default
+	Key string `json:"key"`
+	// Message is the default message which is used when Key has no translation,
+	// {name} placeholders are replaced by details
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+	Cause   error          `json:"-"`
+}
+
+var (
+	errorsMu      sync.RWMutex
+	errorRegistry = map[ErrorCode]*AppError{}
+)
+
+// DefineError registers the definition of an error with code, an existing code is replaced
+func DefineError(code ErrorCode, status int, message string) *AppError {
+	errorsMu.Lock()
+	defer errorsMu.Unlock()
+
+	e := &AppError{Code: code, Status: status, Key: string(code), Message: message}
+	errorRegistry[code] = e
+
+	return e
+}
+
+// LookupError returns the definition of code
+func LookupError(code ErrorCode) (*AppError, bool) {
+	errorsMu.RLock()
+	defer errorsMu.RUnlock()
+
+	e, ok := errorRegistry[code]
+	return e, ok
+}
+
+// DefinedErrors returns the definitions of errors sorted by code
+func DefinedErrors() []*AppError {
+	errorsMu.RLock()
+	defer errorsMu.RUnlock()
+
+	defs := make([]*AppError, 0, len(errorRegistry))
+	for _, e := range errorRegistry {
+		defs = append(defs, e)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Code < defs[j].Code })
+
+	return defs
+}
+
+func (e *AppError) Error() string {
+	msg := interpolate(e.Message, e.Details)
+	if e.Cause != nil {
+		return msg + ": " + e.Cause.Error()
+	}
+
+	return msg
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is an AppError with the same code
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code == e.Code
+}
+
+// WithDetails returns a copy of e with details
+func (e *AppError) WithDetails(details map[string]any) *AppError {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// WithCause returns a copy of e which wraps cause
+func (e *AppError) WithCause(cause error) *AppError {
+	c := *e
+	c.Cause = cause
+	return &c
+}
+
+// Localize returns the message of e in lang, the default message is used if lang has no translation
+func (e *AppError) Localize(lang string) string {
+	if msg, ok := Translate(lang, e.Key, e.Details); ok {
+		return msg
+	}
+
+	return interpolate(e.Message, e.Details)
+}
+
+// interpolate replaces {name} placeholders of msg by details
+func interpolate(msg string, details map[string]any) string {
+	if len(details) == 0 || !strings.Contains(msg, "{") {
+		return msg
+	}
+
+	pairs := make([]string, 0, len(details)*2)
+	for k, v := range details {
+		pairs = append(pairs, "{"+k+"}", fmt.Sprint(v))
+	}
+
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+// appErrorOf returns the AppError of err
+func appErrorOf(err error) (*AppError, bool) {
+	var appErr *AppError
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
+
+var (
+	// ErrInternal is the definition of unknown errors
+	ErrInternal = DefineError(ErrCodeInternal, http.StatusInternalServerError, "internal server error")
+	// ErrValidation is the definition of ValidationErrors
+	ErrValidation = DefineError(ErrCodeValidation, http.StatusUnprocessableEntity, "validation failed")
+)
@@ -0,0 +1,113 @@
+package simutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/alifakhimi/simple-utils-go/multierror"
+	"github.com/labstack/echo/v4"
+)
+
+func TestAppError(t *testing.T) {
+	errOrderClosed := DefineError("order_closed", http.StatusConflict, "order {id} is closed")
+	RegisterCatalog("fa", map[string]string{"order_closed": "سفارش {id} بسته شده است"})
+
+	err := fmt.Errorf("close: %w", errOrderClosed.WithDetails(map[string]any{"id": 7}).WithCause(errors.New("db")))
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"is", errors.Is(err, errOrderClosed), true},
+		{"is other", errors.Is(err, ErrNotFound), false},
+		{"is multierror", errors.Is(multierror.Join(errors.New("x"), err), errOrderClosed), true},
+		{"error", err.Error(), "close: order 7 is closed: db"},
+		{"status", ErrorToHttpStatusCode(err), http.StatusConflict},
+		{"wrapped package error", ErrorToHttpStatusCode(fmt.Errorf("find: %w", ErrRecordNotFound)), http.StatusNotFound},
+		{"unknown", ErrorToHttpStatusCode(errors.New("x")), http.StatusInternalServerError},
+		{"system item", ErrorToHttpStatusCode(ErrSystemItemDelete), http.StatusForbidden},
+		{"localize fa", errOrderClosed.WithDetails(map[string]any{"id": 7}).Localize("fa"), "سفارش 7 بسته شده است"},
+		{"localize default", errOrderClosed.WithDetails(map[string]any{"id": 7}).Localize("de"), "order 7 is closed"},
+		{"lookup", func() any { e, _ := LookupError("order_closed"); return e }(), errOrderClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"fa-IR,fa;q=0.9,en;q=0.8", "fa"},
+		{"de-DE,en-US;q=0.5", "en"},
+		{"de", "en"},
+		{"en;q=0.5,fa;q=0.9", "fa"},
+		{"invalid;;", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Language", tt.header)
+			if got := AcceptLanguage(r); got != tt.want {
+				t.Errorf("AcceptLanguage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandler_localized(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    ErrorCode
+		wantMessage any
+	}{
+		{name: "multierror", err: multierror.Join(ErrRecordNotFound), wantStatus: http.StatusNotFound, wantCode: ErrCodeRecordNotFound, wantMessage: "رکورد یافت نشد"},
+		{name: "wrapped", err: fmt.Errorf("order 1: %w", ErrNotFound), wantStatus: http.StatusNotFound, wantCode: ErrCodeNotFound, wantMessage: "order 1: یافت نشد"},
+		{
+			name:        "wrapped in multierror",
+			err:         multierror.Join(fmt.Errorf("order 1: %w", ErrNotFound), ErrInvalidRequest),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    ErrCodeNotFound,
+			wantMessage: []any{"order 1: یافت نشد", "درخواست نامعتبر است"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler(false)
+			e.GET("/", func(echo.Context) error { return tt.err })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", "fa-IR")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			var got ResponseTemplate
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != tt.wantStatus || got.ErrorCode != tt.wantCode || !reflect.DeepEqual(got.Message, tt.wantMessage) {
+				t.Errorf("response = %d %+v", rec.Code, got)
+			}
+		})
+	}
+}
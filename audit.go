@@ -0,0 +1,321 @@
+package simutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// AuditAction is the action of an audit log
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// auditOldRecordsKey is the statement setting which keeps the records before update and delete
+const auditOldRecordsKey = "simutils:audit:old"
+
+// auditUserKey is the context key of the user who changes records
+type auditUserKey struct{}
+
+// Auditable models are audited by AuditPlugin
+type Auditable interface {
+	IsAuditable() bool
+}
+
+// AuditChange is the old and new value of a changed column
+type AuditChange struct {
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+// AuditLog records who changed a record and what is changed
+type AuditLog struct {
+	ID        PID         `json:"id,omitempty" gorm:"column:id;primaryKey"`
+	Table     string      `json:"table" gorm:"size:128;index:idx_audit_logs_record"`
+	RecordID  PID         `json:"record_id" gorm:"index:idx_audit_logs_record"`
+	Action    AuditAction `json:"action" gorm:"size:16"`
+	Changes   JSON        `json:"changes,omitempty"`
+	UserID    PID         `json:"user_id,omitempty" gorm:"index"`
+	CreatedAt time.Time   `json:"created_at,omitempty"`
+}
+
+// AuditPlugin writes an AuditLog for every created, updated and deleted record of Auditable models
+// in the transaction of the change. The user is read from context, see WithAuditUser,
+// or from UserID of CommonTableFields.
+// The audit_logs table must be migrated with AutoMigrate(&AuditLog{}).
+type AuditPlugin struct{}
+
+// WithAuditUser returns a context which records the changes by user
+func WithAuditUser(ctx context.Context, user PID) context.Context {
+	return context.WithValue(ctx, auditUserKey{}, user)
+}
+
+// AuditUserFrom returns the user of WithAuditUser
+func AuditUserFrom(ctx context.Context) (PID, bool) {
+	user, ok := ctx.Value(auditUserKey{}).(PID)
+	return user, ok
+}
+
+// AuditHistory returns the audit logs of the record of model with id, oldest first
+func AuditHistory(db *gorm.DB, model any, id PID) (logs []AuditLog, err error) {
+	stmt := &gorm.Statement{DB: db}
+	if err = stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	err = db.Where(&AuditLog{Table: stmt.Schema.Table, RecordID: id}).Order("id").Find(&logs).Error
+
+	return logs, err
+}
+
+// Name implements gorm.Plugin interface
+func (AuditPlugin) Name() string {
+	return "simutils:audit"
+}
+
+// Initialize implements gorm.Plugin interface
+func (AuditPlugin) Initialize(db *gorm.DB) error {
+	callbacks := []error{
+		db.Callback().Create().After("gorm:create").Register("simutils:audit", auditAfter(AuditCreate)),
+		db.Callback().Update().Before("gorm:update").Register("simutils:audit_before", auditBefore),
+		db.Callback().Update().After("gorm:update").Register("simutils:audit", auditAfter(AuditUpdate)),
+		db.Callback().Delete().Before("gorm:delete").Register("simutils:audit_before", auditBefore),
+		db.Callback().Delete().After("gorm:delete").Register("simutils:audit", auditAfter(AuditDelete)),
+	}
+
+	for _, err := range callbacks {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isAudited reports whether the model of statement is Auditable
+func isAudited(db *gorm.DB) bool {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return false
+	}
+
+	a, ok := reflect.New(db.Statement.Schema.ModelType).Interface().(Auditable)
+
+	return ok && a.IsAuditable()
+}
+
+// auditBefore loads the records which are going to change
+func auditBefore(db *gorm.DB) {
+	if !isAudited(db) {
+		return
+	}
+
+	records, err := auditLoad(db)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+
+	db.Statement.Settings.Store(auditOldRecordsKey, records)
+}
+
+// auditAfter writes the audit logs of the changed records
+func auditAfter(action AuditAction) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if !isAudited(db) || db.RowsAffected == 0 {
+			return
+		}
+
+		var (
+			sch     = db.Statement.Schema
+			ctx     = db.Statement.Context
+			olds    = map[PID]reflect.Value{}
+			news    []reflect.Value
+			changed = auditValues(db.Statement.ReflectValue)
+		)
+
+		if v, ok := db.Statement.Settings.Load(auditOldRecordsKey); ok {
+			for _, rv := range v.([]reflect.Value) {
+				olds[auditRecordID(ctx, sch, rv)] = rv
+			}
+		}
+
+		switch action {
+		case AuditCreate:
+			news = changed
+		case AuditUpdate:
+			// reload to get the stored values of expressions and batch updates
+			records, err := auditLoadByID(db, olds)
+			if err != nil {
+				_ = db.AddError(err)
+				return
+			}
+			news = records
+		}
+
+		var logs []AuditLog
+		for id, old := range olds {
+			var rv reflect.Value
+			for _, n := range news {
+				if auditRecordID(ctx, sch, n) == id {
+					rv = n
+				}
+			}
+
+			if changes := auditChanges(ctx, sch, old, rv); action == AuditDelete || len(changes) > 0 {
+				logs = append(logs, newAuditLog(db, action, id, old, changes))
+			}
+		}
+
+		if action == AuditCreate {
+			for _, rv := range news {
+				logs = append(logs, newAuditLog(db, action, auditRecordID(ctx, sch, rv), rv, auditChanges(ctx, sch, reflect.Value{}, rv)))
+			}
+		}
+
+		if len(logs) > 0 {
+			_ = db.AddError(db.Session(&gorm.Session{NewDB: true}).Create(&logs).Error)
+		}
+	}
+}
+
+// auditLoad loads the records matched by the conditions of statement
+func auditLoad(db *gorm.DB) ([]reflect.Value, error) {
+	var (
+		stmt = db.Statement
+		tx   = db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table)
+	)
+
+	if stmt.Unscoped {
+		tx = tx.Unscoped()
+	}
+
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			tx = tx.Clauses(where)
+		}
+	}
+
+	// the primary key of the updated or deleted model
+	var ids []any
+	for _, rv := range auditValues(stmt.ReflectValue) {
+		if id, zero := stmt.Schema.PrioritizedPrimaryField.ValueOf(stmt.Context, rv); !zero {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) > 0 {
+		tx = tx.Where(clause.IN{Column: clause.Column{Table: stmt.Table, Name: stmt.Schema.PrioritizedPrimaryField.DBName}, Values: ids})
+	} else if _, ok := stmt.Clauses["WHERE"]; !ok {
+		return nil, nil
+	}
+
+	return auditFind(tx, stmt.Schema)
+}
+
+// auditLoadByID loads the records of ids after update
+func auditLoadByID(db *gorm.DB, records map[PID]reflect.Value) ([]reflect.Value, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]any, 0, len(records))
+	for id := range records {
+		ids = append(ids, id)
+	}
+
+	tx := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).Unscoped().
+		Where(clause.IN{Column: clause.Column{Name: db.Statement.Schema.PrioritizedPrimaryField.DBName}, Values: ids})
+
+	return auditFind(tx, db.Statement.Schema)
+}
+
+func auditFind(tx *gorm.DB, sch *schema.Schema) ([]reflect.Value, error) {
+	records := reflect.New(reflect.SliceOf(sch.ModelType))
+	if err := tx.Find(records.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	return auditValues(records.Elem()), nil
+}
+
+// auditValues returns the structs of a struct or slice value
+func auditValues(rv reflect.Value) (values []reflect.Value) {
+	rv = reflect.Indirect(rv)
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, auditValues(rv.Index(i))...)
+		}
+	case reflect.Struct:
+		values = append(values, rv)
+	}
+
+	return values
+}
+
+func auditRecordID(ctx context.Context, sch *schema.Schema, rv reflect.Value) PID {
+	id, _ := sch.PrioritizedPrimaryField.ValueOf(ctx, rv)
+	return Parse(id)
+}
+
+// auditChanges compares the columns of old and new records, invalid records have no value
+func auditChanges(ctx context.Context, sch *schema.Schema, old, new reflect.Value) map[string]AuditChange {
+	changes := map[string]AuditChange{}
+
+	valueOf := func(f *schema.Field, rv reflect.Value) (any, []byte) {
+		if !rv.IsValid() {
+			return nil, nil
+		}
+		v, _ := f.ValueOf(ctx, rv)
+		b, _ := json.Marshal(v)
+		return v, b
+	}
+
+	for _, f := range sch.Fields {
+		if f.DBName == "" || f.AutoUpdateTime > 0 || f.AutoCreateTime > 0 {
+			continue
+		}
+
+		oldValue, oldJSON := valueOf(f, old)
+		newValue, newJSON := valueOf(f, new)
+		if !bytes.Equal(oldJSON, newJSON) {
+			changes[f.DBName] = AuditChange{Old: oldValue, New: newValue}
+		}
+	}
+
+	return changes
+}
+
+func newAuditLog(db *gorm.DB, action AuditAction, id PID, rv reflect.Value, changes map[string]AuditChange) AuditLog {
+	user, ok := AuditUserFrom(db.Statement.Context)
+	if !ok {
+		if f := db.Statement.Schema.LookUpField("UserID"); f != nil {
+			v, _ := f.ValueOf(db.Statement.Context, rv)
+			user = Parse(v)
+		}
+	}
+
+	var changesJSON JSON
+	if len(changes) > 0 {
+		changesJSON, _ = json.Marshal(changes)
+	}
+
+	return AuditLog{
+		Table:    db.Statement.Schema.Table,
+		RecordID: id,
+		Action:   action,
+		Changes:  changesJSON,
+		UserID:   user,
+	}
+}
@@ -0,0 +1,85 @@
+package simutils
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type auditTestItem struct {
+	CommonTableFields
+	Name  string
+	Price int
+}
+
+func (auditTestItem) IsAuditable() bool { return true }
+
+func TestAuditPlugin(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:audit_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+
+	if err := db.Use(AuditPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&AuditLog{}, &auditTestItem{}, &listTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithAuditUser(context.Background(), 7)
+	item := auditTestItem{CommonTableFields: CommonTableFields{ID: 1}, Name: "pen", Price: 10}
+	if err := db.WithContext(ctx).Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.WithContext(ctx).Model(&item).Update("price", 20)
+	db.WithContext(ctx).Model(&auditTestItem{}).Where("name = ?", "pen").Update("name", "pencil")
+	db.WithContext(ctx).Model(&item).Update("price", 20)
+	db.WithContext(ctx).Delete(&item)
+
+	// not auditable
+	db.Create(&listTestItem{Model: Model{ID: 1}, Name: "x"})
+
+	logs, err := AuditHistory(db, &auditTestItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		action  AuditAction
+		changes map[string]AuditChange
+	}{
+		{action: AuditCreate, changes: map[string]AuditChange{"name": {New: "pen"}, "price": {New: float64(10)}}},
+		{action: AuditUpdate, changes: map[string]AuditChange{"price": {Old: float64(10), New: float64(20)}}},
+		{action: AuditUpdate, changes: map[string]AuditChange{"name": {Old: "pen", New: "pencil"}}},
+		{action: AuditDelete},
+	}
+	if len(logs) != len(tests) {
+		t.Fatalf("AuditHistory() = %d logs, want %d", len(logs), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			log := logs[i]
+			if log.Action != tt.action || log.UserID != 7 || log.Table != "audit_test_items" {
+				t.Errorf("AuditHistory()[%d] = %+v", i, log)
+			}
+
+			var changes map[string]AuditChange
+			if err := json.Unmarshal(log.Changes, &changes); err != nil {
+				t.Fatal(err)
+			}
+			for col, want := range tt.changes {
+				if got := changes[col]; got != want {
+					t.Errorf("AuditHistory()[%d] %s = %v, want %v", i, col, got, want)
+				}
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&AuditLog{}).Where("`table` = ?", "list_test_items").Count(&count)
+	if count != 0 {
+		t.Errorf("not auditable model has %d logs", count)
+	}
+}
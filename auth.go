@@ -0,0 +1,618 @@
+package simutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnauthorized = DefineError(ErrCodeUnauthorized, http.StatusUnauthorized, "authentication is required")
+	ErrInvalidToken = DefineError(ErrCodeInvalidToken, http.StatusUnauthorized, "invalid or expired token")
+	ErrTokenRevoked = DefineError(ErrCodeTokenRevoked, http.StatusUnauthorized, "token is revoked")
+	ErrForbidden    = DefineError(ErrCodeForbidden, http.StatusForbidden, "access is denied")
+
+	ErrAuthKey       = errors.New("invalid auth key")
+	ErrAuthNoKey     = errors.New("auth keys are required")
+	ErrAuthAlgorithm = errors.New("unsupported auth key algorithm")
+	ErrAuthNoLoader  = errors.New("auth user loader is required")
+)
+
+const (
+	// CTXClaims is the context key of the claims of the authenticated request
+	CTXClaims string = "x-ctx-claims"
+
+	// TokenTypeAccess is the type of access tokens
+	TokenTypeAccess = "access"
+	// TokenTypeRefresh is the type of refresh tokens
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	// DefaultAccessTokenTTL is the lifetime of access tokens when AccessTTL is not set
+	DefaultAccessTokenTTL = 15 * time.Minute
+	// DefaultRefreshTokenTTL is the lifetime of refresh tokens when RefreshTTL is not set
+	DefaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+type (
+	// AuthConfig is the config of issuing and verifying JWTs, like
+	//
+	//	{
+	//		"issuer": "shop",
+	//		"signing_key": "2024-06",
+	//		"keys": [
+	//			{"kid": "2024-06", "alg": "EdDSA", "private_key_file": "ed25519.pem"},
+	//			{"kid": "2024-01", "alg": "RS256", "public_key_file": "rsa.pub.pem"}
+	//		],
+	//		"routes": [{"path": "/api/v1"}, {"path": "/api/v1/login", "public": true}]
+	//	}
+	AuthConfig struct {
+		Issuer   string `json:"issuer,omitempty"`
+		Audience string `json:"audience,omitempty"`
+		// Keys verify tokens by their kid, keys which are rotated out only need a public key
+		Keys []AuthKey `json:"keys"`
+		// SigningKey is the kid of the key which signs new tokens, the first key by default
+		SigningKey string `json:"signing_key,omitempty"`
+		// AccessTTL is the lifetime of access tokens, DefaultAccessTokenTTL by default
+		AccessTTL Duration `json:"access_ttl,omitempty"`
+		// RefreshTTL is the lifetime of refresh tokens, DefaultRefreshTokenTTL by default
+		RefreshTTL Duration `json:"refresh_ttl,omitempty"`
+		// Routes protect the routes of HttpServer, see AuthRoute
+		Routes []AuthRoute `json:"routes,omitempty"`
+	}
+
+	// AuthKey is a signing key of tokens
+	AuthKey struct {
+		ID string `json:"kid"`
+		// Algorithm is one of HS256, RS256 and EdDSA
+		Algorithm string `json:"alg"`
+		// Secret is the key of HS256
+		Secret string `json:"secret,omitempty"`
+		// PrivateKeyFile is the pem file of the RS256 or EdDSA private key which signs tokens
+		PrivateKeyFile string `json:"private_key_file,omitempty"`
+		// PublicKeyFile is the pem file of the RS256 or EdDSA public key,
+		// it is derived from the private key if it is not set
+		PublicKeyFile string `json:"public_key_file,omitempty"`
+	}
+
+	// AuthRoute protects the routes whose path starts with Path.
+	// The longest matching path is used and routes which match no path are public.
+	AuthRoute struct {
+		// Path is a prefix of the registered route paths like /api/v1/orders
+		Path string `json:"path"`
+		// Roles which are allowed, any authenticated user is allowed if it is empty
+		Roles []string `json:"roles,omitempty"`
+		// Public routes are not authenticated
+		Public bool `json:"public,omitempty"`
+	}
+
+	// Claims are the claims of access and refresh tokens, the subject is the user id
+	Claims struct {
+		jwt.RegisteredClaims
+		ClientKey string `json:"key,omitempty"`
+		Username  string `json:"uname,omitempty"`
+		Role      string `json:"rol,omitempty"`
+		// Status is the status of user when the token is issued, see Authorizer
+		Status UserMode `json:"sts,omitempty"`
+		// Generation is the token generation of user when the token is issued, see UserGeneration
+		Generation int    `json:"gen,omitempty"`
+		TokenType  string `json:"typ"`
+	}
+
+	// TokenPair is the response of issuing tokens
+	TokenPair struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+		// ExpiresIn is the lifetime of the access token in seconds
+		ExpiresIn int `json:"expires_in"`
+	}
+
+	// TokenStore keeps the ids of revoked tokens until they expire.
+	// Revoke reports false if id was already revoked, the check and the revoke are atomic.
+	TokenStore interface {
+		Revoke(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+		IsRevoked(ctx context.Context, id string) (bool, error)
+	}
+
+	// UserLoader loads the user of id and its current role when tokens are refreshed,
+	// it returns an error if the user can not sign in anymore
+	UserLoader func(ctx context.Context, id PID) (user *User, role string, err error)
+
+	// UserGeneration returns the current token generation of the user of id,
+	// tokens which are issued by another generation are revoked
+	UserGeneration func(ctx context.Context, id PID) (int, error)
+
+	// RedisTokenStore keeps revoked tokens in redis
+	RedisTokenStore struct {
+		Client *redis.Client
+		// Prefix of keys, simutils:auth:revoked: by default
+		Prefix string
+	}
+
+	// MemoryTokenStore keeps revoked tokens in memory, it is only suitable for a single instance
+	MemoryTokenStore struct {
+		mu      sync.Mutex
+		revoked map[string]time.Time
+	}
+
+	// Authenticator issues and verifies tokens of AuthConfig
+	Authenticator struct {
+		config  AuthConfig
+		keys    map[string]*authKey
+		signing *authKey
+		methods []string
+		store   TokenStore
+		// storeOnce resolves the default store, see tokenStore
+		storeOnce sync.Once
+		load      UserLoader
+		gen       UserGeneration
+	}
+
+	authKey struct {
+		id     string
+		method jwt.SigningMethod
+		sign   crypto.PrivateKey
+		verify crypto.PublicKey
+	}
+)
+
+// NewAuthenticator loads the keys of config, revoked tokens are kept in store.
+// If store is nil it is resolved on the first use of tokens, so the redis of InitCache is used
+// even if the cache is initialized after the authenticator, otherwise a MemoryTokenStore.
+func NewAuthenticator(config AuthConfig, store TokenStore) (*Authenticator, error) {
+	if len(config.Keys) == 0 {
+		return nil, ErrAuthNoKey
+	}
+
+	a := &Authenticator{config: config, keys: make(map[string]*authKey), store: store}
+	for _, k := range config.Keys {
+		key, err := k.load()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := a.keys[key.id]; ok {
+			return nil, fmt.Errorf("%w: duplicate kid %s", ErrAuthKey, key.id)
+		}
+
+		a.keys[key.id] = key
+		a.methods = append(a.methods, key.method.Alg())
+	}
+
+	a.signing = a.keys[DefaultIfZero(config.SigningKey, config.Keys[0].ID)]
+	if a.signing == nil || a.signing.sign == nil {
+		return nil, fmt.Errorf("%w: signing key %s has no private key", ErrAuthKey, config.SigningKey)
+	}
+
+	return a, nil
+}
+
+// tokenStore returns the store of revoked tokens, the default store is resolved once
+func (a *Authenticator) tokenStore() TokenStore {
+	a.storeOnce.Do(func() {
+		if a.store != nil {
+			return
+		}
+
+		if c := GetInstanse(); c != nil && c.Active && c.RedisClient != nil {
+			a.store = &RedisTokenStore{Client: c.RedisClient}
+		} else {
+			logrus.Warnln("auth: no active redis cache, revoked tokens are kept in memory of this instance only")
+			a.store = NewMemoryTokenStore()
+		}
+	})
+
+	return a.store
+}
+
+// load parses the secret or pem files of k
+func (k AuthKey) load() (*authKey, error) {
+	if k.ID == "" {
+		return nil, fmt.Errorf("%w: kid is required", ErrAuthKey)
+	}
+
+	key := &authKey{id: k.ID}
+
+	switch k.Algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		if k.Secret == "" || k.Secret == Secret {
+			return nil, fmt.Errorf("%w: %s needs a secret", ErrAuthKey, k.ID)
+		}
+		key.method, key.sign, key.verify = jwt.SigningMethodHS256, []byte(k.Secret), []byte(k.Secret)
+	case jwt.SigningMethodRS256.Alg():
+		key.method = jwt.SigningMethodRS256
+		if err := k.loadPEM(key, func(b []byte) (crypto.PrivateKey, error) {
+			return jwt.ParseRSAPrivateKeyFromPEM(b)
+		}, func(b []byte) (crypto.PublicKey, error) {
+			return jwt.ParseRSAPublicKeyFromPEM(b)
+		}); err != nil {
+			return nil, err
+		}
+		if key.verify == nil {
+			key.verify = key.sign.(*rsa.PrivateKey).Public()
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		key.method = jwt.SigningMethodEdDSA
+		if err := k.loadPEM(key, jwt.ParseEdPrivateKeyFromPEM, jwt.ParseEdPublicKeyFromPEM); err != nil {
+			return nil, err
+		}
+		if key.verify == nil {
+			key.verify = key.sign.(ed25519.PrivateKey).Public()
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrAuthAlgorithm, k.Algorithm)
+	}
+
+	return key, nil
+}
+
+func (k AuthKey) loadPEM(key *authKey, private func([]byte) (crypto.PrivateKey, error), public func([]byte) (crypto.PublicKey, error)) error {
+	if k.PrivateKeyFile == "" && k.PublicKeyFile == "" {
+		return fmt.Errorf("%w: %s needs a private or public key file", ErrAuthKey, k.ID)
+	}
+
+	if k.PrivateKeyFile != "" {
+		b, err := os.ReadFile(k.PrivateKeyFile)
+		if err != nil {
+			return err
+		}
+		if key.sign, err = private(b); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrAuthKey, k.ID, err)
+		}
+	}
+
+	if k.PublicKeyFile != "" {
+		b, err := os.ReadFile(k.PublicKeyFile)
+		if err != nil {
+			return err
+		}
+		if key.verify, err = public(b); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrAuthKey, k.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Issue returns a new access and refresh token of user with role
+func (a *Authenticator) Issue(user *User, role, clientKey string) (*TokenPair, error) {
+	return a.issue(&Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID.String()},
+		ClientKey:        clientKey,
+		Username:         user.Username,
+		Role:             role,
+		Status:           user.Status,
+		Generation:       user.TokenGeneration,
+	})
+}
+
+func (a *Authenticator) issue(subject *Claims) (*TokenPair, error) {
+	accessTTL := DefaultIfZero(a.config.AccessTTL.Duration, DefaultAccessTokenTTL)
+
+	access, err := a.sign(subject, TokenTypeAccess, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := a.sign(subject, TokenTypeRefresh, DefaultIfZero(a.config.RefreshTTL.Duration, DefaultRefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTTL.Seconds()),
+	}, nil
+}
+
+func (a *Authenticator) sign(subject *Claims, tokenType string, ttl time.Duration) (string, error) {
+	id, err := RandStringCode(24)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   subject.Subject,
+			Issuer:    a.config.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		ClientKey:  subject.ClientKey,
+		Username:   subject.Username,
+		Role:       subject.Role,
+		Status:     subject.Status,
+		Generation: subject.Generation,
+		TokenType:  tokenType,
+	}
+	if a.config.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{a.config.Audience}
+	}
+
+	token := jwt.NewWithClaims(a.signing.method, claims)
+	token.Header["kid"] = a.signing.id
+
+	return token.SignedString(a.signing.sign)
+}
+
+// Verify returns the claims of a valid access token which is not revoked
+func (a *Authenticator) Verify(ctx context.Context, token string) (*Claims, error) {
+	return a.verify(ctx, token, TokenTypeAccess)
+}
+
+func (a *Authenticator) verify(ctx context.Context, token, tokenType string) (*Claims, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods(a.methods), jwt.WithExpirationRequired()}
+	if a.config.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.config.Issuer))
+	}
+	if a.config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(a.config.Audience))
+	}
+
+	claims := new(Claims)
+	if _, err := jwt.ParseWithClaims(token, claims, a.keyOf, opts...); err != nil {
+		return nil, ErrInvalidToken.WithCause(err)
+	}
+
+	if claims.TokenType != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	if revoked, err := a.tokenStore().IsRevoked(ctx, claims.ID); err != nil {
+		return nil, err
+	} else if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	if a.gen != nil {
+		if gen, err := a.gen(ctx, claims.UserID()); err != nil {
+			return nil, err
+		} else if gen != claims.Generation {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	return claims, nil
+}
+
+// keyOf returns the verifying key of the kid of token
+func (a *Authenticator) keyOf(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(a.keys) == 1 {
+		kid = a.signing.id
+	}
+
+	key, ok := a.keys[kid]
+	if !ok || key.verify == nil {
+		return nil, fmt.Errorf("%w: unknown kid %q", ErrAuthKey, kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("%w: %s is not the algorithm of %s", ErrAuthAlgorithm, token.Method.Alg(), kid)
+	}
+
+	return key.verify, nil
+}
+
+// SetUserLoader sets the loader of users which are refreshed, see Refresh
+func (a *Authenticator) SetUserLoader(load UserLoader) {
+	a.load = load
+}
+
+// SetUserGeneration sets the token generation of users which is checked on every verify,
+// so all tokens of a user are revoked by changing its generation
+func (a *Authenticator) SetUserGeneration(gen UserGeneration) {
+	a.gen = gen
+}
+
+// Refresh revokes refreshToken and returns a new token pair of its user which is reloaded
+// by the UserLoader, so the current username and role are issued.
+// A refresh token is only used once, concurrent refreshes of a token fail with ErrTokenRevoked.
+func (a *Authenticator) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if a.load == nil {
+		return nil, ErrAuthNoLoader
+	}
+
+	claims, err := a.verify(ctx, refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	user, role, err := a.load(ctx, claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := a.tokenStore().Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrTokenRevoked
+	}
+
+	return a.Issue(user, role, claims.ClientKey)
+}
+
+// Revoke revokes an access or refresh token until it expires
+func (a *Authenticator) Revoke(ctx context.Context, token string) error {
+	claims, err := a.verify(ctx, token, TokenTypeAccess)
+	if errors.Is(err, ErrInvalidToken) {
+		claims, err = a.verify(ctx, token, TokenTypeRefresh)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = a.tokenStore().Revoke(ctx, claims.ID, claims.ExpiresAt.Time)
+	return err
+}
+
+// Middleware authenticates requests by their bearer token and sets the current user and claims of Context,
+// users must have one of roles if they are given
+func (a *Authenticator) Middleware(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			c := ContextOf(ctx)
+			if err := a.authenticate(c, roles); err != nil {
+				return err
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// RoutesMiddleware authenticates the requests of the routes of AuthConfig, see AuthRoute
+func (a *Authenticator) RoutesMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			route := a.routeOf(ctx.Path())
+			if route == nil || route.Public {
+				return next(ctx)
+			}
+
+			c := ContextOf(ctx)
+			if err := a.authenticate(c, route.Roles); err != nil {
+				return err
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// routeOf returns the route of AuthConfig with the longest path which matches path
+func (a *Authenticator) routeOf(path string) (route *AuthRoute) {
+	for i, r := range a.config.Routes {
+		prefix := strings.TrimSuffix(r.Path, "/")
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		if route == nil || len(prefix) > len(strings.TrimSuffix(route.Path, "/")) {
+			route = &a.config.Routes[i]
+		}
+	}
+
+	return
+}
+
+func (a *Authenticator) authenticate(c *Context, roles []string) error {
+	token, ok := bearerToken(c.Request())
+	if !ok {
+		return ErrUnauthorized
+	}
+
+	claims, err := a.Verify(c.Request().Context(), token)
+	if err != nil {
+		return err
+	}
+
+	if len(roles) > 0 && !ArrayElementExists(roles, claims.Role) {
+		return ErrForbidden
+	}
+
+	c.Set(CTXClaims, claims)
+	c.SetCurrentUser(&User{Model: Model{ID: claims.UserID()}, Username: claims.Username, Status: claims.Status})
+
+	return nil
+}
+
+// bearerToken returns the token of the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get(echo.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+// UserID returns the subject of claims
+func (c *Claims) UserID() PID {
+	return Parse(c.Subject)
+}
+
+// Claims returns the claims of the authenticated request
+func (c *Context) Claims() (*Claims, bool) {
+	claims, ok := c.Get(CTXClaims).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// Role returns the role of the authenticated user, it is empty if the request is not authenticated
+func (c *Context) Role() string {
+	if claims, ok := c.Claims(); ok {
+		return claims.Role
+	}
+
+	return ""
+}
+
+// Revoke keeps id in redis until expiresAt by SETNX
+func (s *RedisTokenStore) Revoke(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return false, nil
+	}
+
+	return s.Client.SetNX(ctx, s.key(id), 1, ttl).Result()
+}
+
+// IsRevoked reports whether id is revoked
+func (s *RedisTokenStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	n, err := s.Client.Exists(ctx, s.key(id)).Result()
+	return n > 0, err
+}
+
+func (s *RedisTokenStore) key(id string) string {
+	return DefaultIfZero(s.Prefix, "simutils:auth:revoked:") + id
+}
+
+// NewMemoryTokenStore returns an empty MemoryTokenStore
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{revoked: make(map[string]time.Time)}
+}
+
+// Revoke keeps id until expiresAt
+func (s *MemoryTokenStore) Revoke(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, exp := range s.revoked {
+		if now.After(exp) {
+			delete(s.revoked, k)
+		}
+	}
+	if _, ok := s.revoked[id]; ok || !now.Before(expiresAt) {
+		return false, nil
+	}
+	s.revoked[id] = expiresAt
+
+	return true, nil
+}
+
+// IsRevoked reports whether id is revoked
+func (s *MemoryTokenStore) IsRevoked(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exp, ok := s.revoked[id]
+	return ok && time.Now().Before(exp), nil
+}
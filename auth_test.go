@@ -0,0 +1,351 @@
+package simutils
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+)
+
+// writeAuthTestKey writes the pem files of a new RS256 or EdDSA key and returns their paths
+func writeAuthTestKey(t *testing.T, alg string) (privateFile, publicFile string) {
+	t.Helper()
+
+	var (
+		private any
+		public  any
+	)
+	switch alg {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		private, public = key, key.Public()
+	case "EdDSA":
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		private, public = key, pub
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privateFile = filepath.Join(dir, "private.pem")
+	publicFile = filepath.Join(dir, "public.pem")
+	if err := os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestAuthenticator(t *testing.T) {
+	rsaPrivate, rsaPublic := writeAuthTestKey(t, "RS256")
+	edPrivate, _ := writeAuthTestKey(t, "EdDSA")
+	user := &User{Model: Model{ID: 7}, Username: "u"}
+
+	tests := []struct {
+		name    string
+		keys    []AuthKey
+		wantErr error
+	}{
+		{name: "HS256", keys: []AuthKey{{ID: "k1", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}}},
+		{name: "RS256", keys: []AuthKey{{ID: "k1", Algorithm: "RS256", PrivateKeyFile: rsaPrivate}}},
+		{name: "EdDSA", keys: []AuthKey{{ID: "k1", Algorithm: "EdDSA", PrivateKeyFile: edPrivate}}},
+		{name: "no keys", wantErr: ErrAuthNoKey},
+		{name: "placeholder secret", keys: []AuthKey{{ID: "k1", Algorithm: "HS256", Secret: Secret}}, wantErr: ErrAuthKey},
+		{name: "public signing key", keys: []AuthKey{{ID: "k1", Algorithm: "RS256", PublicKeyFile: rsaPublic}}, wantErr: ErrAuthKey},
+		{name: "unknown algorithm", keys: []AuthKey{{ID: "k1", Algorithm: "none"}}, wantErr: ErrAuthAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAuthenticator(AuthConfig{Issuer: "test", Keys: tt.keys}, NewMemoryTokenStore())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("NewAuthenticator() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			pair, err := a.Issue(user, "admin", "client")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := a.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrAuthNoLoader) {
+				t.Errorf("Refresh(no loader) error = %v, want %v", err, ErrAuthNoLoader)
+			}
+			a.SetUserLoader(func(_ context.Context, id PID) (*User, string, error) {
+				if id != user.ID {
+					return nil, "", ErrInvalidToken
+				}
+				return &User{Model: Model{ID: id}, Username: "renamed"}, "clerk", nil
+			})
+
+			claims, err := a.Verify(ctx, pair.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.UserID() != user.ID || claims.Username != "u" || claims.Role != "admin" || claims.ClientKey != "client" {
+				t.Errorf("Verify() = %+v", claims)
+			}
+
+			if _, err := a.Verify(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify(refresh token) error = %v, want %v", err, ErrInvalidToken)
+			}
+
+			refreshed, err := a.Refresh(ctx, pair.RefreshToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims, err := a.Verify(ctx, refreshed.AccessToken); err != nil || claims.Username != "renamed" || claims.Role != "clerk" || claims.ClientKey != "client" {
+				t.Errorf("Verify(refreshed token) = %+v, %v", claims, err)
+			}
+			if _, err := a.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("Refresh(used token) error = %v, want %v", err, ErrTokenRevoked)
+			}
+
+			if err := a.Revoke(ctx, refreshed.AccessToken); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := a.Verify(ctx, refreshed.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("Verify(revoked token) error = %v, want %v", err, ErrTokenRevoked)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_RefreshOnce(t *testing.T) {
+	a, err := NewAuthenticator(AuthConfig{Keys: []AuthKey{{ID: "k1", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}}}, NewMemoryTokenStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &User{Model: Model{ID: 7}}
+	a.SetUserLoader(func(context.Context, PID) (*User, string, error) { return user, "", nil })
+
+	pair, err := a.Issue(user, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		refreshed atomic.Int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := a.Refresh(context.Background(), pair.RefreshToken); err == nil {
+				refreshed.Add(1)
+			} else if !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("Refresh() error = %v, want %v", err, ErrTokenRevoked)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if refreshed.Load() != 1 {
+		t.Errorf("Refresh() succeeded %d times, want 1", refreshed.Load())
+	}
+}
+
+func TestAuthenticator_Generation(t *testing.T) {
+	a, err := NewAuthenticator(AuthConfig{Keys: []AuthKey{{ID: "k1", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &User{Model: Model{ID: 7}, TokenGeneration: 1}
+	a.SetUserLoader(func(context.Context, PID) (*User, string, error) { return user, "", nil })
+	a.SetUserGeneration(func(context.Context, PID) (int, error) { return user.TokenGeneration, nil })
+
+	pair, err := a.Issue(user, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Verify(context.Background(), pair.AccessToken); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	user.TokenGeneration++
+	if _, err := a.Verify(context.Background(), pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Verify(old generation) error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if _, err := a.Refresh(context.Background(), pair.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Refresh(old generation) error = %v, want %v", err, ErrTokenRevoked)
+	}
+}
+
+func TestAuthenticator_LazyStore(t *testing.T) {
+	defer func(cache *Cache) { c = cache }(c)
+	c = nil
+
+	a, err := NewAuthenticator(AuthConfig{Keys: []AuthKey{{ID: "k1", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cache is initialized after the authenticator like ReadConfig and InitCache
+	if err := InitCache(&Cache{Active: true, RedisOptions: &redis.Options{Addr: "127.0.0.1:0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.tokenStore().(*RedisTokenStore); !ok {
+		t.Errorf("tokenStore() = %T, want *RedisTokenStore", a.tokenStore())
+	}
+}
+
+func TestAuthenticator_Rotation(t *testing.T) {
+	rsaPrivate, rsaPublic := writeAuthTestKey(t, "RS256")
+	edPrivate, _ := writeAuthTestKey(t, "EdDSA")
+	user := &User{Model: Model{ID: 1}}
+
+	old, err := NewAuthenticator(AuthConfig{Keys: []AuthKey{{ID: "old", Algorithm: "RS256", PrivateKeyFile: rsaPrivate}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := old.Issue(user, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := NewAuthenticator(AuthConfig{
+		SigningKey: "new",
+		Keys: []AuthKey{
+			{ID: "old", Algorithm: "RS256", PublicKeyFile: rsaPublic},
+			{ID: "new", Algorithm: "EdDSA", PrivateKeyFile: edPrivate},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rotated.Verify(context.Background(), pair.AccessToken); err != nil {
+		t.Errorf("Verify(token of old key) error = %v", err)
+	}
+
+	unknown, err := NewAuthenticator(AuthConfig{Keys: []AuthKey{{ID: "new", Algorithm: "EdDSA", PrivateKeyFile: edPrivate}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unknown.Verify(context.Background(), pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(token of unknown kid) error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	expired, err := NewAuthenticator(AuthConfig{
+		AccessTTL: Duration{-time.Minute},
+		Keys:      []AuthKey{{ID: "old", Algorithm: "RS256", PrivateKeyFile: rsaPrivate}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair, err = expired.Issue(user, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expired.Verify(context.Background(), pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(expired token) error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestHttpServer_Auth(t *testing.T) {
+	h := &HttpServer{HttpServerConfig: HttpServerConfig{
+		Prefix: "/api",
+		Auth: &AuthConfig{
+			Keys: []AuthKey{{ID: "k1", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}},
+			Routes: []AuthRoute{
+				{Path: "/api/orders"},
+				{Path: "/api/orders/public", Public: true},
+				{Path: "/api/reports", Roles: []string{"admin"}},
+			},
+		},
+	}}
+	if err := h.newEcho(); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := func(ectx echo.Context) error {
+		ctx := ContextOf(ectx)
+		user, _ := ctx.CurrentUser()
+		if user == nil {
+			return ctx.String(http.StatusOK, "anonymous")
+		}
+		return ctx.String(http.StatusOK, user.Username+":"+ctx.Role())
+	}
+	h.PrefixGroup().GET("/orders", handler)
+	h.PrefixGroup().GET("/orders/public", handler)
+	h.PrefixGroup().GET("/reports", handler)
+	h.AuthGroup("/admin", "admin").GET("/users", handler)
+
+	user := &User{Model: Model{ID: 3}, Username: "u"}
+	pair, err := h.Authenticator().Issue(user, "clerk", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "protected", path: "/api/orders", token: pair.AccessToken, wantStatus: http.StatusOK, wantBody: "u:clerk"},
+		{name: "no token", path: "/api/orders", wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", path: "/api/orders", token: "x.y.z", wantStatus: http.StatusUnauthorized},
+		{name: "refresh token", path: "/api/orders", token: pair.RefreshToken, wantStatus: http.StatusUnauthorized},
+		{name: "public", path: "/api/orders/public", wantStatus: http.StatusOK, wantBody: "anonymous"},
+		{name: "role", path: "/api/reports", token: pair.AccessToken, wantStatus: http.StatusForbidden},
+		{name: "group role", path: "/api/admin/users", token: pair.AccessToken, wantStatus: http.StatusForbidden},
+		{name: "unprotected", path: "/api/healthinfo", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+			h.echo.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package simutils
+
+import (
+	"errors"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserOwnerType is the OwnerType of PolymorphicFields of records which are owned by users
+var UserOwnerType = "users"
+
+const (
+	// PermissionOwn is the scope suffix of permissions which are granted only on owned records
+	PermissionOwn = "own"
+
+	// CTXOwnerScope is the context key of the user whose records are listed by RegisterResource,
+	// it is set by ResourceAuthorizer for the roles which can only read their own records
+	CTXOwnerScope string = "x-ctx-owner-scope"
+)
+
+type (
+	// Permission is resource:action like orders:write, resource and action can be globs like shop.* or *.
+	// orders:write:own grants the permission only on the records which are owned by the user, see IsOwner.
+	Permission string
+
+	// Policy maps roles to permissions, like
+	//
+	//	{"roles": {"admin": ["*"], "clerk": ["orders:read", "orders:write:own"]}}
+	Policy struct {
+		Roles map[string][]Permission `json:"roles"`
+	}
+
+	// RolePermission is a permission of a role which is stored in the role_permissions table, see LoadPolicy
+	RolePermission struct {
+		Role       string     `json:"role" gorm:"primaryKey;size:64"`
+		Permission Permission `json:"permission" gorm:"primaryKey;size:128"`
+	}
+
+	// Authorizer authorizes the role of the authenticated user of requests by a Policy
+	Authorizer struct {
+		mu     sync.RWMutex
+		policy Policy
+	}
+)
+
+// NewAuthorizer returns an authorizer of policy
+func NewAuthorizer(policy Policy) *Authorizer {
+	return &Authorizer{policy: policy}
+}
+
+// LoadPolicy returns the policy of the role_permissions table
+func LoadPolicy(db *gorm.DB) (Policy, error) {
+	var perms []RolePermission
+	if err := db.Order("role, permission").Find(&perms).Error; err != nil {
+		return Policy{}, err
+	}
+
+	policy := Policy{Roles: make(map[string][]Permission)}
+	for _, p := range perms {
+		policy.Roles[p.Role] = append(policy.Roles[p.Role], p.Permission)
+	}
+
+	return policy, nil
+}
+
+// SetPolicy replaces the policy, so policies can be reloaded without restart
+func (a *Authorizer) SetPolicy(policy Policy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.policy = policy
+}
+
+// Can reports whether role has perm on all records
+func (a *Authorizer) Can(role string, perm Permission) bool {
+	return a.grants(role, perm, false)
+}
+
+// CanOwn reports whether role has perm on its own records, it is true if role has perm on all records
+func (a *Authorizer) CanOwn(role string, perm Permission) bool {
+	return a.grants(role, perm, true)
+}
+
+func (a *Authorizer) grants(role string, perm Permission, own bool) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, granted := range a.policy.Roles[role] {
+		if granted.matches(perm, own) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches reports whether p grants perm, own permissions match only if own is true
+func (p Permission) matches(perm Permission, own bool) bool {
+	resource, action, scope := p.split()
+	if scope == PermissionOwn && !own {
+		return false
+	}
+
+	wantResource, wantAction, _ := perm.split()
+
+	return globMatch(resource, wantResource) && globMatch(action, wantAction)
+}
+
+// split returns the resource, action and scope of p, action is * if it is not set
+func (p Permission) split() (resource, action, scope string) {
+	parts := strings.SplitN(string(p), ":", 3)
+	resource, action = parts[0], "*"
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	if len(parts) > 2 {
+		scope = parts[2]
+	}
+
+	return
+}
+
+func globMatch(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+// Authorize returns nil if the user of ctx has perm on all records
+func (a *Authorizer) Authorize(ctx echo.Context, perm Permission) error {
+	return a.authorize(ctx, perm, nil, false)
+}
+
+// AuthorizeRecord returns nil if the user of ctx has perm on all records,
+// or has the own permission and owns record, see IsOwner
+func (a *Authorizer) AuthorizeRecord(ctx echo.Context, perm Permission, record any) error {
+	return a.authorize(ctx, perm, record, true)
+}
+
+func (a *Authorizer) authorize(ctx echo.Context, perm Permission, record any, checkOwner bool) error {
+	c := ContextOf(ctx)
+
+	user, ok := c.CurrentUser()
+	if !ok {
+		return ErrUnauthorized
+	}
+	if user.Status != 0 && user.Status != USER_STATUS_ACTIVE {
+		return ErrForbidden
+	}
+
+	role := c.Role()
+	if a.Can(role, perm) {
+		return nil
+	}
+
+	if checkOwner && a.CanOwn(role, perm) && IsOwner(user.ID, record) {
+		return nil
+	}
+
+	return ErrForbidden
+}
+
+// Require allows the requests of users who have all perms on all records
+//
+//	g.DELETE("/orders/:id", deleteOrder, authorizer.Require("orders:delete"))
+func (a *Authorizer) Require(perms ...Permission) echo.MiddlewareFunc {
+	return a.require(a.Can, perms)
+}
+
+// RequireOwn allows the requests of users who have all perms on all or their own records.
+// Handlers must check the records of own permissions by AuthorizeRecord.
+//
+//	g.PUT("/orders/:id", updateOrder, authorizer.RequireOwn("orders:write"))
+func (a *Authorizer) RequireOwn(perms ...Permission) echo.MiddlewareFunc {
+	return a.require(a.CanOwn, perms)
+}
+
+func (a *Authorizer) require(can func(role string, perm Permission) bool, perms []Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			c := ContextOf(ctx)
+			if _, ok := c.CurrentUser(); !ok {
+				return ErrUnauthorized
+			}
+
+			for _, perm := range perms {
+				if !can(c.Role(), perm) {
+					return ErrForbidden
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// ResourceAuthorizer returns a ResourceOptions.Authorize which requires name:read on list and get
+// and name:write on the other actions. The roles which only have name:read:own list their own records,
+// see CTXOwnerScope, and the roles which only have name:write:own create records of ResourceOptions.Owned.
+func ResourceAuthorizer[T any](a *Authorizer, name string) func(echo.Context, ResourceAction, *T) error {
+	return func(ctx echo.Context, action ResourceAction, item *T) error {
+		perm := Permission(name + ":write")
+		if action == ResourceList || action == ResourceGet {
+			perm = Permission(name + ":read")
+		}
+
+		if item != nil {
+			return a.AuthorizeRecord(ctx, perm, item)
+		}
+
+		err := a.Authorize(ctx, perm)
+		if action != ResourceList || !errors.Is(err, ErrForbidden) {
+			return err
+		}
+
+		c := ContextOf(ctx)
+		if user, ok := c.CurrentUser(); ok && a.CanOwn(c.Role(), perm) && OwnerScope(user.ID, new(T)) != nil {
+			c.Set(CTXOwnerScope, user.ID)
+			return nil
+		}
+
+		return err
+	}
+}
+
+// OwnerScope returns a scope of the records of model which are owned by user, see IsOwner.
+// It returns nil if model has no owner fields.
+func OwnerScope(user PID, model any) func(*gorm.DB) *gorm.DB {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var (
+		conds  []clause.Expression
+		column = func(name string) clause.Column { return clause.Column{Table: clause.CurrentTable, Name: name} }
+	)
+
+	if _, ok := t.FieldByName("UserID"); ok {
+		conds = append(conds, clause.Eq{Column: column("user_id"), Value: user})
+	}
+	_, hasType := t.FieldByName("OwnerType")
+	if _, hasID := t.FieldByName("OwnerID"); hasType && hasID {
+		conds = append(conds, clause.And(
+			clause.Eq{Column: column("owner_type"), Value: UserOwnerType},
+			clause.Eq{Column: column("owner_id"), Value: user},
+		))
+	}
+
+	if len(conds) == 0 {
+		return nil
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.Or(conds...))
+	}
+}
+
+// setOwner sets user as the owner of record by the UserID of CommonTableFields,
+// or the OwnerID and OwnerType of PolymorphicFields if record has no UserID
+func setOwner(user PID, record any) {
+	rv := reflect.Indirect(reflect.ValueOf(record))
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+
+	if userID := rv.FieldByName("UserID"); userID.IsValid() && userID.CanSet() && userID.Type() == reflect.TypeOf(user) {
+		userID.Set(reflect.ValueOf(user))
+		return
+	}
+
+	ownerType, ownerID := rv.FieldByName("OwnerType"), rv.FieldByName("OwnerID")
+	if ownerType.IsValid() && ownerType.CanSet() && ownerType.Kind() == reflect.String &&
+		ownerID.IsValid() && ownerID.CanSet() && ownerID.Type() == reflect.TypeOf(user) {
+		ownerType.SetString(UserOwnerType)
+		ownerID.Set(reflect.ValueOf(user))
+	}
+}
+
+// IsOwner reports whether record is owned by user by the UserID of CommonTableFields
+// or the OwnerID of PolymorphicFields whose OwnerType is UserOwnerType
+func IsOwner(user PID, record any) bool {
+	if !user.IsValid() || record == nil {
+		return false
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(record))
+	if rv.Kind() != reflect.Struct {
+		return false
+	}
+
+	if userID := rv.FieldByName("UserID"); userID.IsValid() {
+		if id, ok := userID.Interface().(PID); ok && id == user {
+			return true
+		}
+	}
+
+	ownerType, ownerID := rv.FieldByName("OwnerType"), rv.FieldByName("OwnerID")
+	if ownerType.IsValid() && ownerID.IsValid() && ownerType.Kind() == reflect.String && ownerType.String() == UserOwnerType {
+		id, ok := ownerID.Interface().(PID)
+		return ok && id == user
+	}
+
+	return false
+}
@@ -0,0 +1,309 @@
+package simutils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type authorizeTestOrder struct {
+	CommonTableFields
+	PolymorphicFields
+}
+
+func TestAuthorizer_Can(t *testing.T) {
+	a := NewAuthorizer(Policy{Roles: map[string][]Permission{
+		"admin":  {"*"},
+		"clerk":  {"orders:read", "orders:write:own", "shop.*:read"},
+		"viewer": {"*:read"},
+	}})
+
+	tests := []struct {
+		role    string
+		perm    Permission
+		want    bool
+		wantOwn bool
+	}{
+		{"admin", "orders:delete", true, true},
+		{"clerk", "orders:read", true, true},
+		{"clerk", "orders:write", false, true},
+		{"clerk", "shop.products:read", true, true},
+		{"clerk", "shop.products:write", false, false},
+		{"clerk", "users:read", false, false},
+		{"viewer", "users:read", true, true},
+		{"viewer", "users:write", false, false},
+		{"unknown", "orders:read", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+" "+string(tt.perm), func(t *testing.T) {
+			if got := a.Can(tt.role, tt.perm); got != tt.want {
+				t.Errorf("Can() = %v, want %v", got, tt.want)
+			}
+			if got := a.CanOwn(tt.role, tt.perm); got != tt.wantOwn {
+				t.Errorf("CanOwn() = %v, want %v", got, tt.wantOwn)
+			}
+		})
+	}
+}
+
+func TestIsOwner(t *testing.T) {
+	tests := []struct {
+		name   string
+		record any
+		want   bool
+	}{
+		{"user id", &authorizeTestOrder{CommonTableFields: CommonTableFields{UserID: 1}}, true},
+		{"other user id", authorizeTestOrder{CommonTableFields: CommonTableFields{UserID: 2}}, false},
+		{"polymorphic owner", &authorizeTestOrder{PolymorphicFields: PolymorphicFields{OwnerType: UserOwnerType, OwnerID: 1}}, true},
+		{"polymorphic other type", &authorizeTestOrder{PolymorphicFields: PolymorphicFields{OwnerType: "shops", OwnerID: 1}}, false},
+		{"no owner fields", &User{Model: Model{ID: 1}}, false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsOwner(1, tt.record); got != tt.want {
+				t.Errorf("IsOwner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_Require(t *testing.T) {
+	a := NewAuthorizer(Policy{Roles: map[string][]Permission{
+		"admin": {"orders:*"},
+		"clerk": {"orders:read", "orders:write:own"},
+	}})
+
+	orders := map[string]*authorizeTestOrder{
+		"1": {CommonTableFields: CommonTableFields{UserID: 1}},
+		"2": {CommonTableFields: CommonTableFields{UserID: 2}},
+	}
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			c := ContextOf(ctx)
+			if role := ctx.Request().Header.Get("role"); role != "" {
+				c.Set(CTXClaims, &Claims{Role: role})
+				c.SetCurrentUser(&User{Model: Model{ID: 1}})
+			}
+			return next(c)
+		}
+	})
+	e.HTTPErrorHandler = HTTPErrorHandler(false)
+	e.PUT("/orders/:id", func(ctx echo.Context) error {
+		if err := a.AuthorizeRecord(ctx, "orders:write", orders[ctx.Param("id")]); err != nil {
+			return err
+		}
+		return ctx.NoContent(http.StatusNoContent)
+	}, a.RequireOwn("orders:write"))
+	e.POST("/orders/:id/close", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusNoContent)
+	}, a.Require("orders:write"))
+	e.DELETE("/orders/:id", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusNoContent)
+	}, a.Require("orders:delete"))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		role       string
+		wantStatus int
+	}{
+		{"anonymous", http.MethodPut, "/orders/1", "", http.StatusUnauthorized},
+		{"admin", http.MethodPut, "/orders/2", "admin", http.StatusNoContent},
+		{"own record", http.MethodPut, "/orders/1", "clerk", http.StatusNoContent},
+		{"other record", http.MethodPut, "/orders/2", "clerk", http.StatusForbidden},
+		{"own permission without record check", http.MethodPost, "/orders/1/close", "clerk", http.StatusForbidden},
+		{"all records", http.MethodPost, "/orders/1/close", "admin", http.StatusNoContent},
+		{"no permission", http.MethodDelete, "/orders/1", "clerk", http.StatusForbidden},
+		{"wildcard action", http.MethodDelete, "/orders/1", "admin", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("role", tt.role)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:authorize_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	if err := db.AutoMigrate(&RolePermission{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create([]RolePermission{{"clerk", "orders:read"}, {"clerk", "orders:write:own"}, {"admin", "*"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadPolicy(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewAuthorizer(Policy{})
+	if a.Can("admin", "orders:read") {
+		t.Fatal("Can() = true before SetPolicy")
+	}
+
+	a.SetPolicy(policy)
+	if !a.Can("admin", "users:write") || !a.CanOwn("clerk", "orders:write") || a.Can("clerk", "orders:write") {
+		t.Errorf("LoadPolicy() = %v", policy.Roles)
+	}
+
+	ctx := ContextOf(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder()))
+	ctx.Set(CTXClaims, &Claims{Role: "admin"})
+	ctx.SetCurrentUser(&User{Model: Model{ID: 1}, Status: USER_STATUS_INACTIVE})
+	if err := a.Authorize(ctx, "orders:read"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Authorize(inactive user) error = %v, want %v", err, ErrForbidden)
+	}
+}
+
+func TestAuthorizer_InactiveUser(t *testing.T) {
+	auth, err := NewAuthenticator(AuthConfig{Keys: []AuthKey{{ID: "k1", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}}}, NewMemoryTokenStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAuthorizer(Policy{Roles: map[string][]Permission{"admin": {"*"}}})
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler(false)
+	e.GET("/orders", func(ctx echo.Context) error {
+		if err := a.Authorize(ctx, "orders:read"); err != nil {
+			return err
+		}
+		return ctx.NoContent(http.StatusNoContent)
+	}, auth.Middleware())
+
+	tests := []struct {
+		name       string
+		status     UserMode
+		wantStatus int
+	}{
+		{"active", USER_STATUS_ACTIVE, http.StatusNoContent},
+		{"inactive", USER_STATUS_INACTIVE, http.StatusForbidden},
+		{"pending", PENDING, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := auth.Issue(&User{Model: Model{ID: 1}, Status: tt.status}, "admin", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+pair.AccessToken)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestResourceAuthorizer(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:resource_authorizer_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	if err := db.AutoMigrate(&resourceTestCategory{}, &resourceTestOwnedItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create([]*resourceTestOwnedItem{
+		{CommonTableFields: CommonTableFields{UserID: 1}, Name: "a"},
+		{CommonTableFields: CommonTableFields{UserID: 2}, Name: "b"},
+		{PolymorphicFields: PolymorphicFields{OwnerType: UserOwnerType, OwnerID: 1}, Name: "c"},
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewAuthorizer(Policy{Roles: map[string][]Permission{
+		"admin":  {"items:*"},
+		"clerk":  {"items:read:own"},
+		"writer": {"items:read:own", "items:write:own"},
+	}})
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			c := ContextOf(ctx)
+			c.Set(CTXClaims, &Claims{Role: ctx.Request().Header.Get("role")})
+			c.SetCurrentUser(&User{Model: Model{ID: 1}})
+			return next(c)
+		}
+	})
+	RegisterResource(e.Group("/items"), db, ResourceOptions[resourceTestOwnedItem]{
+		Owned:     true,
+		Authorize: ResourceAuthorizer[resourceTestOwnedItem](a, "items"),
+	})
+
+	tests := []struct {
+		name       string
+		role       string
+		method     string
+		body       string
+		wantStatus int
+		wantNames  []string
+	}{
+		{"all records", "admin", http.MethodGet, "", http.StatusOK, []string{"a", "b", "c"}},
+		{"own records", "clerk", http.MethodGet, "", http.StatusOK, []string{"a", "c"}},
+		{"no permission", "viewer", http.MethodGet, "", http.StatusForbidden, nil},
+		{"create without write", "clerk", http.MethodPost, `{"name":"d"}`, http.StatusForbidden, nil},
+		{"create own record", "writer", http.MethodPost, `{"name":"d","user_id":2}`, http.StatusCreated, nil},
+		{"created record is listed", "writer", http.MethodGet, "", http.StatusOK, []string{"a", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/items?sort=id:asc", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("role", tt.role)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantNames == nil {
+				return
+			}
+
+			var got Response[[]resourceTestOwnedItem]
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, len(got.Data))
+			for i, item := range got.Data {
+				names[i] = item.Name
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
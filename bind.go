@@ -0,0 +1,198 @@
+package simutils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+)
+
+// Bind binds the path params, query params, headers and body of request to a new T,
+// sanitizes it by `sanitize` tags and validates it by `valid` tags of govalidator.
+// Errors of path, query and header binding and invalid fields are returned as ValidationErrors
+// which responds 422 by HTTPErrorHandler.
+//
+//	type CreateOrder struct {
+//		ShopID PID    `param:"shop_id" valid:"required"`
+//		Phone  string `json:"phone" sanitize:"trim,digits" valid:"numeric,required"`
+//	}
+//
+//	req, err := simutils.Bind[CreateOrder](ctx)
+func Bind[T any](ctx echo.Context) (*T, error) {
+	v := new(T)
+	if err := BindAndValidate(ctx, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// BindAndValidate binds the request to v like Bind
+func BindAndValidate(ctx echo.Context, v any) error {
+	var (
+		b    = &echo.DefaultBinder{}
+		errs ValidationErrors
+	)
+
+	for _, source := range []struct {
+		name string
+		bind func(echo.Context, any) error
+	}{
+		{"path", b.BindPathParams},
+		{"query", b.BindQueryParams},
+		{"header", b.BindHeaders},
+	} {
+		if err := source.bind(ctx, v); err != nil {
+			errs = append(errs, FieldError{Field: source.name, Message: bindErrorMessage(err), Rule: "bind"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if err := b.BindBody(ctx, v); err != nil {
+		return err
+	}
+
+	Sanitize(v)
+
+	return ValidateStruct(v)
+}
+
+// ValidateStruct validates the struct of v by `valid` tags of govalidator,
+// fields are named by their json, query, param or header tag
+func ValidateStruct(v any) error {
+	if t := reflect.TypeOf(v); t == nil || indirectType(t).Kind() != reflect.Struct {
+		return nil
+	}
+
+	if _, err := govalidator.ValidateStruct(v); err != nil {
+		fields := fieldErrorsOf(err)
+		if fields == nil {
+			return err
+		}
+
+		for i, f := range fields {
+			fields[i].Field = requestFieldName(reflect.TypeOf(v), strings.Split(f.Field, "."))
+		}
+
+		return fields
+	}
+
+	return nil
+}
+
+// Sanitize cleans the string fields of v which are tagged with `sanitize`, nested structs are sanitized too.
+// The comma separated sanitizers are
+//
+//	trim: removes leading and trailing spaces
+//	digits: converts Persian and Arabic digits to ASCII
+//	fa: normalizes Persian text by NormalizePersian
+//	lower, upper: changes the case
+func Sanitize(v any) {
+	sanitizeValue(reflect.ValueOf(v), "")
+}
+
+func sanitizeValue(rv reflect.Value, tag string) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !rv.IsNil() {
+			sanitizeValue(rv.Elem(), tag)
+		}
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if f := rv.Field(i); f.CanSet() || t.Field(i).Anonymous {
+				sanitizeValue(f, t.Field(i).Tag.Get("sanitize"))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			sanitizeValue(rv.Index(i), tag)
+		}
+	case reflect.String:
+		if tag != "" && rv.CanSet() {
+			rv.SetString(sanitizeString(rv.String(), tag))
+		}
+	}
+}
+
+func sanitizeString(s, tag string) string {
+	for _, name := range strings.Split(tag, ",") {
+		switch strings.TrimSpace(name) {
+		case "trim":
+			s = strings.TrimSpace(s)
+		case "digits":
+			s = strings.Map(func(r rune) rune {
+				if n, ok := persianNormalForms[r]; ok && n >= '0' && n <= '9' {
+					return n
+				}
+				return r
+			}, s)
+		case "fa":
+			s = NormalizePersian(s)
+		case "lower":
+			s = strings.ToLower(s)
+		case "upper":
+			s = strings.ToUpper(s)
+		}
+	}
+
+	return s
+}
+
+// requestFieldName returns the request name of the field path of t
+func requestFieldName(t reflect.Type, path []string) string {
+	names := make([]string, 0, len(path))
+
+	for _, name := range path {
+		t = indirectType(t)
+		for t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = indirectType(t.Elem())
+		}
+
+		f, ok := t.FieldByName(name)
+		if t.Kind() != reflect.Struct || !ok {
+			names = append(names, name)
+			continue
+		}
+
+		names = append(names, tagName(f))
+		t = f.Type
+	}
+
+	return strings.Join(names, ".")
+}
+
+// tagName returns the name of f in json, query, param or header tag, the field name if it has no tag
+func tagName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "query", "param", "header", "form"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return f.Name
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// bindErrorMessage returns the message of a binding error of echo
+func bindErrorMessage(err error) string {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return fmt.Sprint(httpErr.Message)
+	}
+
+	return err.Error()
+}
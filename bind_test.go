@@ -0,0 +1,120 @@
+package simutils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type bindTestAddress struct {
+	City string `json:"city" sanitize:"trim" valid:"required"`
+}
+
+type bindTestRequest struct {
+	ShopID  string          `param:"shop_id" valid:"numeric"`
+	Limit   int             `query:"limit"`
+	Tenant  string          `header:"X-Tenant" sanitize:"lower"`
+	Name    string          `json:"name" sanitize:"trim,fa" valid:"required"`
+	Phone   string          `json:"phone" sanitize:"trim,digits" valid:"numeric,required"`
+	Email   string          `json:"email,omitempty" valid:"email"`
+	Address bindTestAddress `json:"address"`
+}
+
+func newBindTestContext(target, body string) echo.Context {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-Tenant", "ACME")
+
+	ctx := echo.New().NewContext(req, httptest.NewRecorder())
+	ctx.SetParamNames("shop_id")
+	ctx.SetParamValues("12")
+
+	return ctx
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		want       *bindTestRequest
+		wantFields []string
+	}{
+		{
+			name:   "sanitized",
+			target: "/?limit=5",
+			body:   `{"name":" علي ","phone":" ۰۹۱۲۳۴۵۶۷۸۹ ","address":{"city":" Tehran "}}`,
+			want: &bindTestRequest{
+				ShopID:  "12",
+				Limit:   5,
+				Tenant:  "acme",
+				Name:    "علی",
+				Phone:   "09123456789",
+				Address: bindTestAddress{City: "Tehran"},
+			},
+		},
+		{
+			name:       "invalid fields",
+			target:     "/",
+			body:       `{"name":"  ","phone":"09a","email":"x","address":{}}`,
+			wantFields: []string{"address.city", "email", "name", "phone"},
+		},
+		{
+			name:       "invalid query",
+			target:     "/?limit=ten",
+			body:       `{"name":"a","phone":"1","address":{"city":"a"}}`,
+			wantFields: []string{"query"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Bind[bindTestRequest](newBindTestContext(tt.target, tt.body))
+
+			if tt.wantFields != nil {
+				var errs ValidationErrors
+				if !errors.As(err, &errs) {
+					t.Fatalf("Bind() error = %v, want ValidationErrors", err)
+				}
+
+				fields := make([]string, 0, len(errs))
+				for _, e := range errs {
+					fields = append(fields, e.Field)
+				}
+				slices.Sort(fields)
+				if !slices.Equal(fields, tt.wantFields) {
+					t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+				}
+				if status := ErrorResponse(err, false).Code; status != http.StatusUnprocessableEntity {
+					t.Errorf("status = %d, want %d", status, http.StatusUnprocessableEntity)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Bind() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bind() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinder(t *testing.T) {
+	ctx := &Context{Context: newBindTestContext("/", `{"name":"a","phone":"x"}`)}
+
+	var req bindTestRequest
+	if _, err := Binder(ctx, &req); err == nil {
+		t.Fatal("Binder() error = nil, want validation error")
+	}
+	if ctx.RequestModel != nil {
+		t.Errorf("RequestModel = %v, want nil", ctx.RequestModel)
+	}
+}
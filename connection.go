@@ -342,3 +342,23 @@ func dialector(dbConn *DBConnection) gorm.Dialector {
 
 	return nil
 }
+
+// DriverOf returns the DatabaseDriver of an opened gorm connection
+func DriverOf(db *gorm.DB) DatabaseDriver {
+	if db == nil || db.Dialector == nil {
+		return 0
+	}
+
+	switch db.Dialector.Name() {
+	case "postgres":
+		return PostgresSQL
+	case "sqlserver":
+		return SQLServer
+	case "sqlite":
+		return SQLite
+	case "mysql":
+		return MySQL
+	}
+
+	return 0
+}
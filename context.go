@@ -0,0 +1,132 @@
+package simutils
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// CTXUser is the context key of the current user
+	CTXUser string = "x-ctx-user"
+	// CTXDB is the context key of the tenant database
+	CTXDB string = "x-ctx-db"
+)
+
+// ContextMiddleware wraps the echo context of requests in *Context, so handlers can use Binder
+// and the typed accessors of Context. It is installed by HttpServer.
+func ContextMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			return next(ContextOf(ctx))
+		}
+	}
+}
+
+// ContextOf returns ctx as *Context, a new Context wraps ctx if it is not *Context
+func ContextOf(ctx echo.Context) *Context {
+	if c, ok := ctx.(*Context); ok {
+		return c
+	}
+
+	return &Context{Context: ctx}
+}
+
+// CurrentUser returns the user of SetCurrentUser
+func (c *Context) CurrentUser() (*User, bool) {
+	user, ok := c.Get(CTXUser).(*User)
+	return user, ok && user != nil
+}
+
+// SetCurrentUser sets the current user of request,
+// the changes of the request context are audited by the user, see WithAuditUser
+func (c *Context) SetCurrentUser(user *User) {
+	c.Set(CTXUser, user)
+	if user != nil {
+		c.SetRequest(c.Request().WithContext(WithAuditUser(c.Request().Context(), user.ID)))
+	}
+}
+
+// DB returns the tenant database of SetDB bound to the request context.
+// The database is never chosen by the client, it must be set by a trusted middleware.
+func (c *Context) DB() (*gorm.DB, error) {
+	db, ok := c.Get(CTXDB).(*gorm.DB)
+	if !ok || db == nil {
+		return nil, ErrInvalidDatabaseConnection
+	}
+
+	return db.WithContext(c.Request().Context()), nil
+}
+
+// SetDB sets the tenant database of request
+func (c *Context) SetDB(db *gorm.DB) {
+	c.Set(CTXDB, db)
+}
+
+// Pagination returns the limit and offset query params, see ParseContext
+func (c *Context) Pagination() (limit, offset int, err error) {
+	if err = c.parseURL(); err != nil {
+		return
+	}
+
+	limit, offset, _, _ = ParseContext(c)
+
+	return
+}
+
+// Filters returns the filters of query params parsed by ParseURL
+func (c *Context) Filters() (map[string][]FilterValue, error) {
+	if err := c.parseURL(); err != nil {
+		return nil, err
+	}
+
+	_, _, filters, _ := ParseContext(c)
+
+	return filters, nil
+}
+
+// Sorts returns the sort query param parsed by ParseURL
+func (c *Context) Sorts() ([]SortValue, error) {
+	if err := c.parseURL(); err != nil {
+		return nil, err
+	}
+
+	_, _, _, sorts := ParseContext(c)
+
+	return sorts, nil
+}
+
+// parseURL runs ParseURL if it did not run before
+func (c *Context) parseURL() error {
+	if c.Get(CTXFilters) != nil {
+		return nil
+	}
+
+	if err := ParseURL(c); err != nil {
+		return ErrInvalidRequest
+	}
+
+	return nil
+}
+
+// RequestID returns the id of request which is set by the request_id middleware or the client
+func (c *Context) RequestID() string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+// Log returns the logger of request with its request id and current user
+func (c *Context) Log() *logrus.Entry {
+	fields := logrus.Fields{"method": c.Request().Method, "path": c.Path()}
+	if id := c.RequestID(); id != "" {
+		fields["request_id"] = id
+	}
+	if user, ok := c.CurrentUser(); ok {
+		fields["user_id"] = user.ID
+	}
+
+	return logrus.WithFields(fields)
+}
@@ -0,0 +1,175 @@
+package simutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestContextMiddleware(t *testing.T) {
+	h := &HttpServer{}
+	if err := h.newEcho(); err != nil {
+		t.Fatal(err)
+	}
+
+	type request struct {
+		Name string `json:"name" valid:"required"`
+	}
+
+	h.echo.POST("/items", func(ectx echo.Context) error {
+		ctx, err := Binder(ectx, &request{})
+		if err != nil {
+			return err
+		}
+
+		limit, offset, err := ctx.Pagination()
+		if err != nil {
+			return err
+		}
+		filters, err := ctx.Filters()
+		if err != nil {
+			return err
+		}
+		sorts, err := ctx.Sorts()
+		if err != nil {
+			return err
+		}
+
+		req, _ := GetRequestModel[*request](ctx)
+
+		return ctx.JSON(http.StatusOK, map[string]any{
+			"name":       req.Name,
+			"limit":      limit,
+			"offset":     offset,
+			"filters":    len(filters["code"]),
+			"sorts":      len(sorts),
+			"request_id": ctx.RequestID(),
+		})
+	})
+
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "bound",
+			target:     "/items?limit=10&offset=20&code=a&sort=-id",
+			body:       `{"name":"a"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"filters":1,"limit":10,"name":"a","offset":20,"request_id":"r1","sorts":1}`,
+		},
+		{
+			name:       "invalid",
+			target:     "/items",
+			body:       `{}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid query",
+			target:     "/items?limit=x",
+			body:       `{"name":"a"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderXRequestID, "r1")
+
+			rec := httptest.NewRecorder()
+			h.echo.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+				t.Errorf("body = %s, want %s", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestContext_DB(t *testing.T) {
+	dbConn := &DBConnection{Name: "context_test", DBConfig: DBConfig{Driver: SQLite, DSN: "file:context_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(dbConn); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		set     bool
+		wantErr bool
+	}{
+		{name: "not set", wantErr: true},
+		{name: "set", set: true},
+		{name: "header is ignored", header: "context_test", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(HeadersDatabase, tt.header)
+			ctx := ContextOf(echo.New().NewContext(req, httptest.NewRecorder()))
+			if tt.set {
+				ctx.SetDB(dbConn.DB)
+			}
+
+			db, err := ctx.DB()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DB() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && db.Statement.Context != req.Context() {
+				t.Error("DB() is not bound to the request context")
+			}
+		})
+	}
+}
+
+func TestContext_CurrentUser(t *testing.T) {
+	ctx := ContextOf(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder()))
+	if _, ok := ctx.CurrentUser(); ok {
+		t.Fatal("CurrentUser() ok = true before SetCurrentUser")
+	}
+
+	user := &User{Model: Model{ID: 7}, Username: "u"}
+	ctx.SetCurrentUser(user)
+
+	if got, ok := ctx.CurrentUser(); !ok || got != user {
+		t.Errorf("CurrentUser() = %v, %v", got, ok)
+	}
+	if id, ok := AuditUserFrom(ctx.Request().Context()); !ok || id != user.ID {
+		t.Errorf("AuditUserFrom() = %v, %v", id, ok)
+	}
+}
+
+func TestContext_Log(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(echo.HeaderXRequestID, "r1")
+	ctx := ContextOf(echo.New().NewContext(req, httptest.NewRecorder()))
+	ctx.SetCurrentUser(&User{Model: Model{ID: 7}})
+
+	if got := ctx.Log().Data; got["request_id"] != "r1" || got["user_id"] != PID(7) || got["method"] != http.MethodGet {
+		t.Errorf("Log() fields = %v", got)
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	limit, offset, filters, sorts := ParseContext(ctx)
+	if limit != 5 || offset != 0 || filters == nil || sorts != nil {
+		t.Errorf("ParseContext() = %d, %d, %v, %v", limit, offset, filters, sorts)
+	}
+}
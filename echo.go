@@ -1,7 +1,6 @@
 package simutils
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
 	"net/url"
@@ -28,17 +27,28 @@ type Context struct {
 	RequestModel any
 }
 
-// Binder attempts to bind a given object to the custom Context
+// Binder attempts to bind a given object to the custom Context,
+// the object is sanitized and validated after binding, see Bind
 func Binder(echoContext echo.Context, i any) (*Context, error) {
 	// Check if echoContext can be cast to *Context
-	if ctx, ok := echoContext.(*Context); !ok {
+	ctx, ok := echoContext.(*Context)
+	if !ok {
 		return nil, ErrBindContextFailed // Return error if casting fails
-	} else if err := ctx.Bind(i); err != nil { // Attempt to bind the object to the context
+	}
+
+	// Attempt to bind the object to the context
+	if err := ctx.Bind(i); err != nil {
 		return nil, err // Return error if binding fails
-	} else {
-		ctx.RequestModel = i // Assign the bound object to RequestModel
-		return ctx, nil      // Return the updated context
 	}
+
+	// Validate the sanitized object
+	Sanitize(i)
+	if err := ValidateStruct(i); err != nil {
+		return nil, err
+	}
+
+	ctx.RequestModel = i // Assign the bound object to RequestModel
+	return ctx, nil      // Return the updated context
 }
 
 // GetRequestModel extracts the RequestModel from Context and performs a type assertion
@@ -58,20 +68,9 @@ func GetRequestModel[T any](ctx *Context) (T, error) {
 	}
 }
 
+// ReplyTemplate responds template as data without converting it to a map, see Respond
 func ReplyTemplate(ctx echo.Context, httpStatus int, err error, template interface{}, meta interface{}) error {
-	data, er := json.Marshal(template) // Convert to a json string
-	if er != nil {
-		return er
-	}
-
-	content := make(map[string]interface{})
-
-	er = json.Unmarshal(data, &content) // Convert to a map
-	if err != nil {
-		return er
-	}
-
-	return Reply(ctx, httpStatus, err, content, meta)
+	return Respond(ctx, httpStatus, template, err, meta)
 }
 
 // Reply ...
@@ -104,10 +103,14 @@ func Reply(ctx echo.Context, httpStatus int, err error, content map[string]inter
 	case http.StatusNotAcceptable:
 		template = ResponseNotAcceptable(content, multierror.Join(err))
 	default:
-		template = ResponseInternalServerError(content, multierror.Join(err))
+		template = NewResponse[any](httpStatus, content, err, meta).Template()
+	}
+
+	if appErr, ok := appErrorOf(err); ok && httpStatus >= http.StatusBadRequest {
+		template.ErrorCode = appErr.Code
 	}
 
-	return ctx.JSON(httpStatus, template)
+	return replyTemplate(ctx, httpStatus, template)
 }
 
 // GetWithCode return template with considering error code
@@ -129,6 +132,8 @@ func GetWithCode(data interface{}, code int, err error) (template *ResponseTempl
 		template = ResponseUnauthorized(data, msg)
 	case http.StatusMethodNotAllowed:
 		template = ResponseMethodNotAllowed(data, msg)
+	case http.StatusTooManyRequests:
+		template = ResponseTooManyRequests(data, msg)
 	default:
 		template = ResponseStatusNotImplemented(data, msg)
 	}
@@ -205,19 +210,9 @@ func ArrayElementExists(a []string, v string) bool {
 	return false
 }
 
+// ErrorToHttpStatusCode returns the status of err like HTTPErrorHandler, unknown errors are 500, see ErrorStatus
 func ErrorToHttpStatusCode(err error) (status int) {
-	switch err {
-	case ErrNotFound, ErrRecordNotFound:
-		status = http.StatusNotFound
-	case ErrInvalidRequest:
-		status = http.StatusBadRequest
-	case ErrAlreadyExist:
-		status = http.StatusNotAcceptable
-
-	default:
-		status = http.StatusNotImplemented
-	}
-
+	status, _ = ErrorStatus(err)
 	return
 }
 
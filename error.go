@@ -2,15 +2,16 @@ package simutils
 
 import (
 	"encoding/json"
-	"errors"
+	"net/http"
 )
 
 var (
-	ErrNotFound         = errors.New("not found")
-	ErrRecordNotFound   = errors.New("record not found")
-	ErrInvalidRequest   = errors.New("invalid request")
-	ErrAlreadyExist     = errors.New("already exist")
-	ErrSystemItemDelete = errors.New("you cant delete system items")
+	ErrNotFound           = DefineError(ErrCodeNotFound, http.StatusNotFound, "not found")
+	ErrRecordNotFound     = DefineError(ErrCodeRecordNotFound, http.StatusNotFound, "record not found")
+	ErrInvalidRequest     = DefineError(ErrCodeInvalidRequest, http.StatusBadRequest, "invalid request")
+	ErrAlreadyExist       = DefineError(ErrCodeAlreadyExist, http.StatusNotAcceptable, "already exist")
+	ErrSystemItemDelete   = DefineError(ErrCodeSystemItemDelete, http.StatusForbidden, "you cant delete system items")
+	ErrForeignKeyViolated = DefineError(ErrCodeForeignKeyViolated, http.StatusConflict, "record is referenced by other records")
 )
 
 type Error string
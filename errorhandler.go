@@ -0,0 +1,213 @@
+package simutils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alifakhimi/simple-utils-go/multierror"
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrorCode is a stable machine-readable code of an error response
+type ErrorCode string
+
+const (
+	ErrCodeNotFound           ErrorCode = "not_found"
+	ErrCodeRecordNotFound     ErrorCode = "record_not_found"
+	ErrCodeInvalidRequest     ErrorCode = "invalid_request"
+	ErrCodeAlreadyExist       ErrorCode = "already_exist"
+	ErrCodeSystemItemDelete   ErrorCode = "system_item_delete"
+	ErrCodeVersionConflict    ErrorCode = "version_conflict"
+	ErrCodeForeignKeyViolated ErrorCode = "foreign_key_violated"
+	ErrCodeValidation         ErrorCode = "validation_failed"
+	ErrCodeInternal           ErrorCode = "internal_error"
+	ErrCodeUnauthorized       ErrorCode = "unauthorized"
+	ErrCodeInvalidToken       ErrorCode = "invalid_token"
+	ErrCodeTokenRevoked       ErrorCode = "token_revoked"
+	ErrCodeForbidden          ErrorCode = "forbidden"
+	ErrCodeUserExists         ErrorCode = "user_exists"
+	ErrCodeInvalidCredentials ErrorCode = "invalid_credentials"
+	ErrCodeUserLocked         ErrorCode = "user_locked"
+	ErrCodeUserNotActive      ErrorCode = "user_not_active"
+	ErrCodeInvalidCode        ErrorCode = "invalid_code"
+	ErrCodeTooManyRequests    ErrorCode = "too_many_requests"
+)
+
+type (
+	// FieldError is the validation error of a request field
+	FieldError struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+		// Rule is the failed validation rule like required or email
+		Rule string `json:"rule,omitempty"`
+	}
+
+	// ValidationErrors is the error of invalid request fields which responds 422
+	ValidationErrors []FieldError
+)
+
+func (es ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Field+": "+e.Message)
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// HTTPErrorHandler returns an echo.HTTPErrorHandler which responds errors by LocalizedErrorResponse
+// in the language of Accept-Language header
+func HTTPErrorHandler(debug bool) echo.HTTPErrorHandler {
+	return func(err error, ctx echo.Context) {
+		if ctx.Response().Committed {
+			return
+		}
+
+		template := LocalizedErrorResponse(err, AcceptLanguage(ctx.Request()), debug)
+		if template.Code >= http.StatusInternalServerError {
+			ctx.Logger().Error(err)
+		}
+
+		if ctx.Request().Method == http.MethodHead {
+			err = ctx.NoContent(template.Code)
+		} else {
+			err = replyTemplate(ctx, template.Code, template)
+		}
+
+		if err != nil {
+			ctx.Logger().Error(err)
+		}
+	}
+}
+
+// ErrorResponse maps err to a ResponseTemplate in DefaultLanguage, see LocalizedErrorResponse
+func ErrorResponse(err error, debug bool) *ResponseTemplate {
+	return LocalizedErrorResponse(err, DefaultLanguage, debug)
+}
+
+// LocalizedErrorResponse maps err to a ResponseTemplate with its status and error code,
+// messages of AppError are translated to lang.
+// Messages of 5xx errors are hidden unless debug is set.
+func LocalizedErrorResponse(err error, lang string, debug bool) *ResponseTemplate {
+	var (
+		status, code = ErrorStatus(err)
+		message      any
+		data         any
+		httpErr      *echo.HTTPError
+		fields       = fieldErrorsOf(err)
+	)
+
+	switch {
+	case status >= http.StatusInternalServerError && !debug:
+		message = ErrInternal.Localize(lang)
+	case fields != nil:
+		message = ErrValidation.Localize(lang)
+		data = fields
+	case errors.As(err, &httpErr):
+		message = httpErr.Message
+	default:
+		message = errorMessages(err, lang)
+	}
+
+	return &ResponseTemplate{
+		Code:      status,
+		Status:    http.StatusText(status),
+		Message:   message,
+		Data:      data,
+		ErrorCode: code,
+	}
+}
+
+// ErrorStatus returns the http status and error code of err, unknown errors are internal errors
+func ErrorStatus(err error) (int, ErrorCode) {
+	var httpErr *echo.HTTPError
+
+	switch err = TranslateGormError(err); {
+	case fieldErrorsOf(err) != nil:
+		return ErrValidation.Status, ErrValidation.Code
+	case errors.As(err, &httpErr):
+		return httpErr.Code, statusErrorCode(httpErr.Code)
+	}
+
+	if appErr, ok := appErrorOf(err); ok {
+		return appErr.Status, appErr.Code
+	}
+
+	return ErrInternal.Status, ErrInternal.Code
+}
+
+// statusErrorCode returns the code of http status like too_many_requests
+func statusErrorCode(status int) ErrorCode {
+	text := http.StatusText(status)
+	if text == "" {
+		return ErrorCode(fmt.Sprintf("http_%d", status))
+	}
+
+	return ErrorCode(strings.ReplaceAll(strings.ToLower(strings.ReplaceAll(text, "-", " ")), " ", "_"))
+}
+
+// fieldErrorsOf returns the field errors of ValidationErrors and govalidator errors
+func fieldErrorsOf(err error) ValidationErrors {
+	var (
+		fields    ValidationErrors
+		validErrs govalidator.Errors
+		validErr  govalidator.Error
+	)
+
+	switch {
+	case errors.As(err, &fields):
+		return fields
+	case errors.As(err, &validErrs):
+		for _, e := range validErrs {
+			fields = append(fields, fieldErrorsOf(e)...)
+		}
+		return fields
+	case errors.As(err, &validErr):
+		return ValidationErrors{{
+			Field:   strings.Join(append(validErr.Path, validErr.Name), "."),
+			Message: validErr.Err.Error(),
+			Rule:    validErr.Validator,
+		}}
+	}
+
+	return nil
+}
+
+// errorMessages returns the message of err or the messages of a multierror,
+// AppErrors are translated to lang even if they are wrapped
+func errorMessages(err error, lang string) any {
+	message := func(err error) string {
+		appErr, ok := appErrorOf(err)
+		if !ok {
+			return err.Error()
+		} else if err == error(appErr) {
+			return appErr.Localize(lang)
+		}
+
+		// the message of a wrapped AppError is translated in place
+		if msg, text := interpolate(appErr.Message, appErr.Details), err.Error(); msg != "" && strings.Contains(text, msg) {
+			return strings.Replace(text, msg, appErr.Localize(lang), 1)
+		}
+		return appErr.Localize(lang)
+	}
+
+	var multiErr *multierror.MultiError
+	if !errors.As(err, &multiErr) {
+		return message(err)
+	}
+
+	errs := multiErr.Errors()
+	if len(errs) == 1 {
+		return message(errs[0])
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, message(e))
+	}
+
+	return msgs
+}
@@ -0,0 +1,128 @@
+package simutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/alifakhimi/simple-utils-go/multierror"
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	type errorHandlerTestUser struct {
+		Email string `valid:"email,required"`
+	}
+	_, validErr := govalidator.ValidateStruct(errorHandlerTestUser{Email: "invalid"})
+
+	tests := []struct {
+		name        string
+		err         error
+		debug       bool
+		wantStatus  int
+		wantCode    ErrorCode
+		wantMessage any
+		wantData    any
+	}{
+		{
+			name:        "http error",
+			err:         echo.NewHTTPError(http.StatusTooManyRequests, "slow down"),
+			wantStatus:  http.StatusTooManyRequests,
+			wantCode:    "too_many_requests",
+			wantMessage: "slow down",
+		},
+		{
+			name:        "not found",
+			err:         fmt.Errorf("order 1: %w", ErrNotFound),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    ErrCodeNotFound,
+			wantMessage: "order 1: not found",
+		},
+		{
+			name:        "gorm record not found",
+			err:         gorm.ErrRecordNotFound,
+			wantStatus:  http.StatusNotFound,
+			wantCode:    ErrCodeRecordNotFound,
+			wantMessage: "record not found",
+		},
+		{
+			name:        "system item",
+			err:         ErrSystemItemDelete,
+			wantStatus:  http.StatusForbidden,
+			wantCode:    ErrCodeSystemItemDelete,
+			wantMessage: ErrSystemItemDelete.Error(),
+		},
+		{
+			name:        "validation",
+			err:         validErr,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    ErrCodeValidation,
+			wantMessage: "validation failed",
+			wantData:    []any{map[string]any{"field": "Email", "message": "invalid does not validate as email", "rule": "email"}},
+		},
+		{
+			name:        "multierror",
+			err:         multierror.Join(ErrInvalidRequest, errors.New("name is empty")),
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    ErrCodeInvalidRequest,
+			wantMessage: []any{"invalid request", "name is empty"},
+		},
+		{
+			name:        "internal hidden",
+			err:         errors.New("dial tcp: connection refused"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    ErrCodeInternal,
+			wantMessage: "internal server error",
+		},
+		{
+			name:        "internal debug",
+			err:         errors.New("dial tcp: connection refused"),
+			debug:       true,
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    ErrCodeInternal,
+			wantMessage: "dial tcp: connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler(tt.debug)
+			e.Logger.SetOutput(io.Discard)
+			e.GET("/", func(echo.Context) error { return tt.err })
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var got struct {
+				Code      int       `json:"code"`
+				ErrorCode ErrorCode `json:"error_code"`
+				Message   any       `json:"message"`
+				Data      any       `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != tt.wantStatus || got.Code != tt.wantStatus {
+				t.Errorf("status = %d, code = %d, want %d", rec.Code, got.Code, tt.wantStatus)
+			}
+			if got.ErrorCode != tt.wantCode {
+				t.Errorf("error_code = %s, want %s", got.ErrorCode, tt.wantCode)
+			}
+			if !reflect.DeepEqual(got.Message, tt.wantMessage) {
+				t.Errorf("message = %#v, want %#v", got.Message, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(got.Data, tt.wantData) {
+				t.Errorf("data = %#v, want %#v", got.Data, tt.wantData)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package simutils
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// FieldSet is a sparse fieldset parsed from `fields` query param like `id,name,owner.name`.
+// Fields are the json names of the model fields, or the column/field names
+// when the field has no json tag.
+type FieldSet struct {
+	// Fields are the json names of the selected fields
+	Fields []string
+	// Columns are the selected columns
+	Columns []string
+	// Relations are the nested fieldsets keyed by the json name of association
+	Relations map[string]*FieldSet
+
+	schema   *schema.Schema
+	relation *schema.Relationship
+}
+
+// ParseFieldSet parses comma separated fields against the schema of model
+func ParseFieldSet(db *gorm.DB, model any, fields string) (*FieldSet, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	fs := newFieldSet(stmt.Schema, nil)
+
+	for _, path := range strings.Split(fields, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+
+		if err := fs.add(strings.Split(path, ".")); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(fs.Fields) == 0 && len(fs.Relations) == 0 {
+		return nil, ErrInvalidRequest
+	}
+
+	return fs, nil
+}
+
+func newFieldSet(sch *schema.Schema, rel *schema.Relationship) *FieldSet {
+	return &FieldSet{
+		Relations: map[string]*FieldSet{},
+		schema:    sch,
+		relation:  rel,
+	}
+}
+
+// add adds the path of a field, all parts except the last one must be associations
+func (fs *FieldSet) add(path []string) error {
+	if len(path) == 1 {
+		f := lookUpSchemaField(fs.schema, path[0])
+		if f == nil || f.DBName == "" || !f.Readable {
+			return ErrInvalidRequest
+		}
+
+		if name := jsonFieldName(f.StructField.Tag.Get("json"), f.Name); !ArrayElementExists(fs.Fields, name) {
+			fs.Fields = append(fs.Fields, name)
+			fs.Columns = append(fs.Columns, f.DBName)
+		}
+
+		return nil
+	}
+
+	rel := lookUpSchemaRelation(fs.schema, path[0])
+	if rel == nil {
+		return ErrInvalidRequest
+	}
+
+	name := jsonFieldName(rel.Field.StructField.Tag.Get("json"), rel.Field.Name)
+	nested, exists := fs.Relations[name]
+	if !exists {
+		nested = newFieldSet(rel.FieldSchema, rel)
+		fs.Relations[name] = nested
+	}
+
+	return nested.add(path[1:])
+}
+
+// Associations returns the association paths of fieldset, like Owner and Owner.Company
+func (fs *FieldSet) Associations() (names []string) {
+	for _, nested := range fs.Relations {
+		names = append(names, nested.relation.Name)
+		for _, n := range nested.Associations() {
+			names = append(names, nested.relation.Name+"."+n)
+		}
+	}
+
+	return names
+}
+
+// Apply selects the columns of fieldset on db and preloads the associations
+// with their selected columns. Keys needed to load associations are selected too.
+func (fs *FieldSet) Apply(db *gorm.DB) *gorm.DB {
+	if columns := fs.selects(); len(columns) > 0 {
+		db = db.Select(columns)
+	}
+
+	return fs.preload(db, "")
+}
+
+func (fs *FieldSet) preload(db *gorm.DB, prefix string) *gorm.DB {
+	for _, nested := range fs.Relations {
+		var (
+			name    = prefix + nested.relation.Name
+			columns = nested.selects()
+		)
+
+		if len(columns) > 0 {
+			db = db.Preload(name, func(tx *gorm.DB) *gorm.DB { return tx.Select(columns) })
+		} else {
+			db = db.Preload(name)
+		}
+
+		db = nested.preload(db, name+".")
+	}
+
+	return db
+}
+
+// selects returns the requested columns with the keys of associations
+func (fs *FieldSet) selects() []string {
+	if len(fs.Columns) == 0 {
+		return nil
+	}
+
+	columns := append([]string{}, fs.Columns...)
+	appendColumn := func(f *schema.Field) {
+		if f != nil && f.DBName != "" && !ArrayElementExists(columns, f.DBName) {
+			columns = append(columns, f.DBName)
+		}
+	}
+
+	for _, f := range fs.schema.PrimaryFields {
+		appendColumn(f)
+	}
+
+	// keys of the association of this fieldset which belong to this schema
+	if fs.relation != nil {
+		for _, ref := range fs.relation.References {
+			if ref.OwnPrimaryKey {
+				appendColumn(ref.ForeignKey)
+			} else {
+				appendColumn(ref.PrimaryKey)
+			}
+		}
+	}
+
+	// keys of nested associations which belong to this schema
+	for _, nested := range fs.Relations {
+		for _, ref := range nested.relation.References {
+			if ref.OwnPrimaryKey {
+				appendColumn(ref.PrimaryKey)
+			} else {
+				appendColumn(ref.ForeignKey)
+			}
+		}
+	}
+
+	return columns
+}
+
+// Filter omits the unselected fields from the json representation of data,
+// data can be a struct, a slice of structs or their pointers
+func (fs *FieldSet) Filter(data any) (any, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+
+	return fs.filter(value), nil
+}
+
+func (fs *FieldSet) filter(value any) any {
+	switch v := value.(type) {
+	case []any:
+		for i := range v {
+			v[i] = fs.filter(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			if nested, ok := fs.Relations[k]; ok {
+				v[k] = nested.filter(v[k])
+			} else if len(fs.Fields) > 0 && !ArrayElementExists(fs.Fields, k) {
+				delete(v, k)
+			}
+		}
+	}
+
+	return value
+}
+
+// lookUpSchemaField finds the field by json name, field name or column
+func lookUpSchemaField(sch *schema.Schema, name string) *schema.Field {
+	for _, f := range sch.Fields {
+		if jsonFieldName(f.StructField.Tag.Get("json"), "") == name {
+			return f
+		}
+	}
+
+	return sch.LookUpField(name)
+}
+
+// lookUpSchemaRelation finds the association by json name or field name
+func lookUpSchemaRelation(sch *schema.Schema, name string) *schema.Relationship {
+	for relName, rel := range sch.Relationships.Relations {
+		if strings.EqualFold(relName, name) || jsonFieldName(rel.Field.StructField.Tag.Get("json"), "") == name {
+			return rel
+		}
+	}
+
+	return nil
+}
+
+// jsonFieldName returns the name part of json tag or def if tag has no name
+func jsonFieldName(tag string, def string) string {
+	if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+		return name
+	}
+
+	return def
+}
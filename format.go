@@ -0,0 +1,488 @@
+package simutils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// ResponseFormat is a format of responses which is negotiated by `format` query param or Accept header
+type ResponseFormat string
+
+const (
+	FormatJSON    ResponseFormat = "json"
+	FormatXML     ResponseFormat = "xml"
+	FormatMsgPack ResponseFormat = "msgpack"
+	// FormatCSV and FormatXLSX export Data of list responses
+	FormatCSV  ResponseFormat = "csv"
+	FormatXLSX ResponseFormat = "xlsx"
+)
+
+const (
+	MIMEApplicationMsgPack = "application/msgpack"
+	MIMETextCSV            = "text/csv"
+	MIMEApplicationXLSX    = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+var (
+	// ExportBatchSize is the number of records which Export loads at once
+	ExportBatchSize = 1000
+	// exportFlushRows is the number of csv rows which are written before flushing the response
+	exportFlushRows = 100
+
+	formatsOfMIME = map[string]ResponseFormat{
+		echo.MIMEApplicationJSON:  FormatJSON,
+		echo.MIMEApplicationXML:   FormatXML,
+		echo.MIMETextXML:          FormatXML,
+		MIMEApplicationMsgPack:    FormatMsgPack,
+		"application/x-msgpack":   FormatMsgPack,
+		"application/vnd.msgpack": FormatMsgPack,
+		MIMETextCSV:               FormatCSV,
+		MIMEApplicationXLSX:       FormatXLSX,
+	}
+
+	xmlNameRegex = regexp.MustCompile(`[^\w.-]`)
+)
+
+// NegotiateFormat returns the format of response by `format` query param or Accept header,
+// csv and xlsx are negotiated only if the response is exportable. JSON is the default format.
+func NegotiateFormat(ctx echo.Context, exportable bool) ResponseFormat {
+	allowed := func(f ResponseFormat) bool {
+		return exportable || (f != FormatCSV && f != FormatXLSX)
+	}
+
+	if f := ResponseFormat(strings.ToLower(ctx.QueryParam("format"))); f != "" {
+		for _, known := range formatsOfMIME {
+			if f == known && allowed(f) {
+				return f
+			}
+		}
+	}
+
+	type accepted struct {
+		format ResponseFormat
+		q      float64
+	}
+
+	var formats []accepted
+	for _, part := range strings.Split(ctx.Request().Header.Get(echo.HeaderAccept), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		if f, ok := formatsOfMIME[mediaType]; ok && allowed(f) && q > 0 {
+			formats = append(formats, accepted{format: f, q: q})
+		}
+	}
+
+	sort.SliceStable(formats, func(i, j int) bool { return formats[i].q > formats[j].q })
+	if len(formats) > 0 {
+		return formats[0].format
+	}
+
+	return FormatJSON
+}
+
+// Render responds template in the negotiated format of JSON, XML and MessagePack
+func Render(ctx echo.Context, status int, template any) error {
+	return render(ctx, status, template, NegotiateFormat(ctx, false))
+}
+
+// RenderList responds a list template like Render, Data is exported if csv or xlsx is negotiated
+func RenderList(ctx echo.Context, status int, template *ResponseTemplate) error {
+	format := NegotiateFormat(ctx, status < http.StatusBadRequest)
+	if format != FormatCSV && format != FormatXLSX {
+		return render(ctx, status, template, format)
+	}
+
+	w, err := newTableWriter(ctx, status, format)
+	if err != nil {
+		return err
+	}
+
+	exp := &tableExporter{w: w}
+	if t := reflect.TypeOf(template.Data); t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		exp.columns = exportColumns(t.Elem())
+	}
+
+	if err := exp.writeItems(template.Data); err != nil {
+		return err
+	}
+
+	return exp.Close()
+}
+
+// Export streams the records of db query as csv or xlsx in batches of ExportBatchSize,
+// the negotiated format is used if format is empty and it is csv if nothing is negotiated
+func Export[T any](ctx echo.Context, db *gorm.DB, format ResponseFormat) error {
+	if format == "" {
+		if format = NegotiateFormat(ctx, true); format != FormatXLSX {
+			format = FormatCSV
+		}
+	}
+
+	w, err := newTableWriter(ctx, http.StatusOK, format)
+	if err != nil {
+		return err
+	}
+
+	var (
+		batch []T
+		exp   = &tableExporter{w: w, columns: exportColumns(reflect.TypeOf(new(T)).Elem())}
+	)
+
+	err = db.WithContext(ctx.Request().Context()).FindInBatches(&batch, ExportBatchSize, func(tx *gorm.DB, _ int) error {
+		return exp.writeItems(batch)
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return exp.Close()
+}
+
+func render(ctx echo.Context, status int, template any, format ResponseFormat) error {
+	switch format {
+	case FormatXML:
+		v, err := jsonValue(template)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString(xml.Header)
+		enc := xml.NewEncoder(&buf)
+		if err := encodeXML(enc, "response", v); err != nil {
+			return err
+		}
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+
+		return ctx.Blob(status, echo.MIMEApplicationXMLCharsetUTF8, buf.Bytes())
+	case FormatMsgPack:
+		v, err := jsonValue(template)
+		if err != nil {
+			return err
+		}
+
+		b, err := msgpack.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		return ctx.Blob(status, MIMEApplicationMsgPack, b)
+	}
+
+	return ctx.JSON(status, template)
+}
+
+// jsonValue converts v to maps, slices and scalars by its json encoding,
+// so json names and marshalers are used by every format
+func jsonValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var result any
+	if err := dec.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return numbersOf(result), nil
+}
+
+// numbersOf converts json numbers to int64 or float64
+func numbersOf(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		for k, e := range t {
+			t[k] = numbersOf(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = numbersOf(e)
+		}
+	}
+
+	return v
+}
+
+// encodeXML writes v as an element of name, items of slices are item elements
+func encodeXML(enc *xml.Encoder, name string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlNameRegex.ReplaceAllString(name, "_")}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case nil:
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if err := encodeXML(enc, k, t[k]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, e := range t {
+			if err := encodeXML(enc, "item", e); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := enc.EncodeToken(xml.CharData(tableCell(t))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// tableWriter writes the rows of csv and xlsx exports
+type tableWriter interface {
+	Write(row []string) error
+	Close() error
+}
+
+func newTableWriter(ctx echo.Context, status int, format ResponseFormat) (tableWriter, error) {
+	resp := ctx.Response()
+	resp.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="export.%s"`, format))
+
+	switch format {
+	case FormatCSV:
+		resp.Header().Set(echo.HeaderContentType, MIMETextCSV+"; charset=utf-8")
+		resp.WriteHeader(status)
+		return &csvTableWriter{resp: resp, w: csv.NewWriter(resp)}, nil
+	case FormatXLSX:
+		f := excelize.NewFile()
+		sw, err := f.NewStreamWriter(f.GetSheetName(0))
+		if err != nil {
+			return nil, err
+		}
+		resp.Header().Set(echo.HeaderContentType, MIMEApplicationXLSX)
+		return &xlsxTableWriter{resp: resp, status: status, f: f, sw: sw}, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported export format %s", ErrInvalidRequest, format)
+}
+
+// csvTableWriter flushes the response every exportFlushRows rows
+type csvTableWriter struct {
+	resp *echo.Response
+	w    *csv.Writer
+	rows int
+}
+
+func (w *csvTableWriter) Write(row []string) error {
+	if err := w.w.Write(row); err != nil {
+		return err
+	}
+
+	if w.rows++; w.rows%exportFlushRows == 0 {
+		w.w.Flush()
+		w.resp.Flush()
+	}
+
+	return w.w.Error()
+}
+
+func (w *csvTableWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// xlsxTableWriter writes rows to a stream writer of excelize which keeps rows in a temp file
+type xlsxTableWriter struct {
+	resp   *echo.Response
+	status int
+	f      *excelize.File
+	sw     *excelize.StreamWriter
+	rows   int
+}
+
+func (w *xlsxTableWriter) Write(row []string) error {
+	values := make([]any, len(row))
+	for i, v := range row {
+		values[i] = v
+	}
+
+	w.rows++
+	cell, err := excelize.CoordinatesToCellName(1, w.rows)
+	if err != nil {
+		return err
+	}
+
+	return w.sw.SetRow(cell, values)
+}
+
+func (w *xlsxTableWriter) Close() error {
+	defer w.f.Close()
+
+	if err := w.sw.Flush(); err != nil {
+		return err
+	}
+
+	w.resp.WriteHeader(w.status)
+	_, err := w.f.WriteTo(w.resp)
+
+	return err
+}
+
+// exportColumns returns the json names of fields of struct t in order, embedded structs are flattened
+func exportColumns(t reflect.Type) []string {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+		switch {
+		case name == "-", !f.IsExported() && !f.Anonymous:
+		case f.Anonymous && name == "":
+			columns = append(columns, exportColumns(f.Type)...)
+		case name == "":
+			columns = append(columns, f.Name)
+		default:
+			columns = append(columns, name)
+		}
+	}
+
+	return columns
+}
+
+// tableExporter writes items by their json values under a header of columns,
+// columns are the sorted keys of the first item if they are empty
+type tableExporter struct {
+	w          tableWriter
+	columns    []string
+	headerDone bool
+}
+
+func (e *tableExporter) writeHeader() error {
+	if e.headerDone {
+		return nil
+	}
+
+	e.headerDone = true
+	return e.w.Write(e.columns)
+}
+
+// writeItems writes the items of slice data
+func (e *tableExporter) writeItems(data any) error {
+	rv := reflect.ValueOf(data)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		v, err := jsonValue(rv.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		item, _ := v.(map[string]any)
+
+		if e.columns == nil {
+			for k := range item {
+				e.columns = append(e.columns, k)
+			}
+			sort.Strings(e.columns)
+		}
+
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+
+		row := make([]string, len(e.columns))
+		for j, col := range e.columns {
+			row[j] = exportCell(item[col])
+		}
+
+		if err := e.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close writes the header of an empty export and closes the writer
+func (e *tableExporter) Close() error {
+	if e.columns != nil {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	return e.w.Close()
+}
+
+// exportCell formats a json value as a cell of csv and xlsx exports,
+// strings which spreadsheets run as formulas are prefixed by a quote
+func exportCell(v any) string {
+	if t, ok := v.(string); ok && t != "" && strings.ContainsRune("=+-@\t\r", rune(t[0])) {
+		return "'" + t
+	}
+
+	return tableCell(v)
+}
+
+// tableCell formats a json value as a cell, objects and arrays are written as json
+func tableCell(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case map[string]any, []any:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,212 @@
+package simutils
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/xuri/excelize/v2"
+)
+
+type formatTestItem struct {
+	ID    int     `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price,omitempty"`
+	Tags  []string
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		accept     string
+		exportable bool
+		want       ResponseFormat
+	}{
+		{"default", "/", "", false, FormatJSON},
+		{"any", "/", "*/*", false, FormatJSON},
+		{"query", "/?format=XML", "application/json", false, FormatXML},
+		{"accept", "/", "application/msgpack", false, FormatMsgPack},
+		{"quality", "/", "application/json;q=0.5, text/xml", false, FormatXML},
+		{"csv of list", "/", "text/csv", true, FormatCSV},
+		{"csv of item", "/?format=csv", "", false, FormatJSON},
+		{"unknown query", "/?format=yaml", "application/xml", false, FormatXML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set(echo.HeaderAccept, tt.accept)
+			ctx := echo.New().NewContext(req, httptest.NewRecorder())
+
+			if got := NegotiateFormat(ctx, tt.exportable); got != tt.want {
+				t.Errorf("NegotiateFormat() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	items := []formatTestItem{{ID: 1, Name: "a", Price: 2.5, Tags: []string{"x"}}, {ID: 2, Name: "b,c"}}
+
+	tests := []struct {
+		name     string
+		target   string
+		handler  echo.HandlerFunc
+		wantType string
+		check    func(t *testing.T, body []byte)
+	}{
+		{
+			name:     "xml",
+			target:   "/?format=xml",
+			handler:  func(ctx echo.Context) error { return OK(ctx, items[0], nil) },
+			wantType: echo.MIMEApplicationXML,
+			check: func(t *testing.T, body []byte) {
+				want := `<response><code>200</code><data><Tags><item>x</item></Tags><id>1</id><name>a</name><price>2.5</price></data><message></message><status>OK</status></response>`
+				if !strings.HasSuffix(string(body), want) {
+					t.Errorf("body = %s, want %s", body, want)
+				}
+			},
+		},
+		{
+			name:   "xml is not escaped as formulas",
+			target: "/?format=xml",
+			handler: func(ctx echo.Context) error {
+				return OK(ctx, map[string]any{"mobile": "+989120000000", "note": "-5"}, nil)
+			},
+			wantType: echo.MIMEApplicationXML,
+			check: func(t *testing.T, body []byte) {
+				if want := `<data><mobile>+989120000000</mobile><note>-5</note></data>`; !strings.Contains(string(body), want) {
+					t.Errorf("body = %s, want %s", body, want)
+				}
+			},
+		},
+		{
+			name:     "msgpack",
+			target:   "/?format=msgpack",
+			handler:  func(ctx echo.Context) error { return OK(ctx, items[0], nil) },
+			wantType: MIMEApplicationMsgPack,
+			check: func(t *testing.T, body []byte) {
+				var got Response[formatTestItem]
+				dec := msgpack.NewDecoder(bytes.NewReader(body))
+				dec.SetCustomStructTag("json")
+				if err := dec.Decode(&got); err != nil {
+					t.Fatal(err)
+				}
+				if got.Code != http.StatusOK || got.Data.Name != "a" || got.Data.Price != 2.5 {
+					t.Errorf("body = %+v", got)
+				}
+			},
+		},
+		{
+			name:     "csv",
+			target:   "/?format=csv",
+			handler:  func(ctx echo.Context) error { return Page(ctx, items, CreatePaginateTemplate(2, 0, 10)) },
+			wantType: MIMETextCSV,
+			check: func(t *testing.T, body []byte) {
+				want := "id,name,price,Tags\n1,a,2.5,\"[\"\"x\"\"]\"\n2,\"b,c\",,\n"
+				if string(body) != want {
+					t.Errorf("body = %q, want %q", body, want)
+				}
+			},
+		},
+		{
+			name:   "csv of maps",
+			target: "/?format=csv",
+			handler: func(ctx echo.Context) error {
+				return RenderList(ctx, http.StatusOK, ResponseOk([]map[string]any{{"name": "a", "id": 1}, {"name": "=HYPERLINK(\"x\")", "id": -2}, {"name": "@SUM(A1)", "id": 3}}, nil, nil))
+			},
+			wantType: MIMETextCSV,
+			check: func(t *testing.T, body []byte) {
+				if want := "id,name\n1,a\n-2,\"'=HYPERLINK(\"\"x\"\")\"\n3,'@SUM(A1)\n"; string(body) != want {
+					t.Errorf("body = %q, want %q", body, want)
+				}
+			},
+		},
+		{
+			name:   "xlsx",
+			target: "/?format=xlsx",
+			handler: func(ctx echo.Context) error {
+				return Page(ctx, append(items, formatTestItem{ID: 3, Name: "=1+1"}), CreatePaginateTemplate(3, 0, 10))
+			},
+			wantType: MIMEApplicationXLSX,
+			check: func(t *testing.T, body []byte) {
+				f, err := excelize.OpenReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+
+				rows, err := f.GetRows(f.GetSheetName(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(rows) != 4 || rows[0][1] != "name" || rows[2][1] != "b,c" || rows[3][1] != "'=1+1" {
+					t.Errorf("rows = %v", rows)
+				}
+				if formula, _ := f.GetCellFormula(f.GetSheetName(0), "B4"); formula != "" {
+					t.Errorf("formula = %s, want none", formula)
+				}
+			},
+		},
+		{
+			name:     "error is not exported",
+			target:   "/?format=csv",
+			handler:  func(ctx echo.Context) error { return Reply(ctx, http.StatusNotFound, ErrNotFound, nil, nil) },
+			wantType: echo.MIMEApplicationJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/", tt.handler)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, tt.wantType) {
+				t.Fatalf("content type = %s, want %s", ct, tt.wantType)
+			}
+			if tt.check != nil {
+				tt.check(t, rec.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:format_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	if err := db.AutoMigrate(&repositoryTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []string{"a", "b", "c"} {
+		if err := db.Create(&repositoryTestItem{Code: code}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ExportBatchSize = 2
+	defer func() { ExportBatchSize = 1000 }()
+
+	e := echo.New()
+	e.GET("/", func(ctx echo.Context) error {
+		return Export[repositoryTestItem](ctx, db.Select("id", "code").Order("id"), "")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], "Code") || !strings.Contains(lines[3], ",c,") {
+		t.Errorf("Export() = %q", rec.Body.String())
+	}
+}
@@ -63,6 +63,9 @@ func GetID(val interface{}) PID {
 func ParseFilters(db *gorm.DB, driver DatabaseDriver, filters map[string][]FilterValue, mapKeyToColumn map[string][]string) (*gorm.DB, error) {
 	var (
 		err error
+		// columns tagged with `normalize` are filtered by their shadow columns
+		normalized = normalizedColumnsOf(db)
+		dataTypes  = columnDataTypesOf(db)
 	)
 
 	for fk, fvs := range filters {
@@ -78,36 +81,30 @@ func ParseFilters(db *gorm.DB, driver DatabaseDriver, filters map[string][]Filte
 				continue
 			}
 
-			if strings.ToLower(fv.Operator) == "in" {
+			if op := strings.ToLower(fv.Operator); op == "in" || op == "nin" {
+				ins := strings.Split(cast.ToString(fv.Value), ",")
 				for _, col := range cols {
-					ins := strings.Split(fv.Value.(string), ",")
-					if len(ins) > 2000 {
-						values := make([]string, len(ins))
-						for i, s := range ins {
-							values[i] = fmt.Sprintf("('%s')", s)
+					values := ins
+					if shadow, _, ok := normalizedColumn(normalized, col, ""); ok {
+						values = make([]string, len(ins))
+						for i, v := range ins {
+							_, values[i], _ = normalizedColumn(normalized, col, v)
 						}
-
-						query = append(query, fmt.Sprintf("%s %s (%s as tbl(id))", col, mapURLToDBOperator[fv.Operator], fmt.Sprintf("select * from (values %s)", strings.Join(values, ","))))
-						// args = append(args, ins)
-					} else {
-						query = append(query, fmt.Sprintf("%s %s (?)", col, mapURLToDBOperator[fv.Operator]))
-						args = append(args, ins)
+						col = shadow
 					}
 
-					/*
-						limit := 1000
-						for offset := 0; offset < len(inArray); offset += limit {
-							query = append(query, fmt.Sprintf("%s %s (select id from (values ))", col, mapURLToDBOperator[fv.Operator]))
-
-							if offset+limit > len(inArray) {
-								limit = len(inArray) - offset
-							}
-
-							args = append(args, inArray[offset:offset+limit])
-						}*/
+					q, a := inListCondition(driver, col, columnDataType(dataTypes, col), op == "nin", values)
+					query = append(query, q)
+					args = append(args, a...)
 				}
 			} else {
 				for _, col := range cols {
+					if shadow, value, ok := normalizedColumn(normalized, col, cast.ToString(fv.Value)); ok {
+						query = append(query, fmt.Sprintf("%s %s ?", shadow, mapURLToDBOperator[fv.Operator]))
+						args = append(args, value)
+						continue
+					}
+
 					query = append(query, fmt.Sprintf("%s %s ?", col, mapURLToDBOperator[fv.Operator]))
 					args = append(args, CorrectSimilarChars(driver, fv.Value))
 				}
@@ -145,6 +142,10 @@ func BuildGormQuery(ctx *context.Context, db *gorm.DB, queryParams url.Values) *
 	// init gorm db
 	qb := db
 
+	// fields are applied after includes to select columns of preloaded associations,
+	// group_by, agg and having are applied after fields to replace the selected columns
+	fields := queryParams.Get("fields")
+
 	for field, values := range queryParams {
 		// continue if value is empty
 		if len(values) == 0 {
@@ -159,6 +160,10 @@ func BuildGormQuery(ctx *context.Context, db *gorm.DB, queryParams url.Values) *
 			for i := 1; i < len(values); i++ {
 				qb = qb.Or("search like ?", values[i])
 			}
+		case "q":
+			if qb.Statement.Model != nil {
+				qb = Search(qb, qb.Statement.Model, values[0])
+			}
 		case "limit":
 			qb = qb.Limit(cast.ToInt(values[0]))
 		case "offset":
@@ -184,6 +189,8 @@ func BuildGormQuery(ctx *context.Context, db *gorm.DB, queryParams url.Values) *
 			for _, inc := range values {
 				qb = qb.Preload(inc)
 			}
+		case "fields", "group_by", "agg", "having":
+			continue
 		default:
 			if len(values) == 1 {
 				qb = qb.Where(fmt.Sprintf("%s = ?", field), values)
@@ -193,5 +200,19 @@ func BuildGormQuery(ctx *context.Context, db *gorm.DB, queryParams url.Values) *
 		}
 	}
 
+	if fields != "" && qb.Statement.Model != nil {
+		if fs, err := ParseFieldSet(qb, qb.Statement.Model, fields); err != nil {
+			_ = qb.AddError(err)
+		} else {
+			qb = fs.Apply(qb)
+		}
+	}
+
+	if agg, err := aggregationOf(qb, queryParams); err != nil {
+		_ = qb.AddError(err)
+	} else if agg != nil {
+		qb = agg.Apply(qb)
+	}
+
 	return qb
 }
@@ -1,7 +1,11 @@
 package simutils
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+
+	"gorm.io/gorm/schema"
 )
 
 func TestGetID(t *testing.T) {
@@ -73,3 +77,95 @@ func TestGetTableName(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFilters(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:parse_filters_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+
+	if err := db.AutoMigrate(&listTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 10; i++ {
+		db.Create(&listTestItem{Model: Model{ID: PID(i)}, Name: fmt.Sprintf("item'%d", i), Price: i})
+	}
+
+	largeList := func(values ...string) string {
+		for i := 0; len(values) <= LargeInListThreshold; i++ {
+			values = append(values, fmt.Sprintf("%d", 1000+i))
+		}
+		return strings.Join(values, ",")
+	}
+
+	columns := map[string][]string{"id": {"id"}, "name": {"name"}}
+	tests := []struct {
+		name    string
+		filters map[string][]FilterValue
+		want    int64
+	}{
+		{name: "in", filters: map[string][]FilterValue{"id": {{Operator: "in", Value: "1,2,3"}}}, want: 3},
+		{name: "nin", filters: map[string][]FilterValue{"id": {{Operator: "nin", Value: "1,2,3"}}}, want: 7},
+		{name: "large in", filters: map[string][]FilterValue{"id": {{Operator: "in", Value: largeList("1", "2")}}}, want: 2},
+		{name: "large nin", filters: map[string][]FilterValue{"id": {{Operator: "nin", Value: largeList("1", "2")}}}, want: 8},
+		{name: "large in text", filters: map[string][]FilterValue{"name": {{Operator: "in", Value: largeList("item'1", "x') OR 1=1 --")}}}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb, err := ParseFilters(db.Model(&listTestItem{}), SQLite, tt.filters, columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got int64
+			if err := qb.Count(&got).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFilters() count = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_inListCondition(t *testing.T) {
+	values := make([]string, LargeInListThreshold+1)
+	for i := range values {
+		values[i] = fmt.Sprintf("%d", i)
+	}
+
+	tests := []struct {
+		name      string
+		driver    DatabaseDriver
+		col       string
+		dataType  schema.DataType
+		values    []string
+		not       bool
+		wantQuery string
+		wantArgs  int
+	}{
+		{name: "small list", driver: PostgresSQL, col: "id", dataType: schema.Int, values: values[:3], wantQuery: "id IN (?)", wantArgs: 1},
+		{name: "postgres", driver: PostgresSQL, col: "id", dataType: schema.Int, values: values, wantQuery: "id = ANY(CAST(? AS bigint[]))", wantArgs: 1},
+		{name: "postgres not", driver: PostgresSQL, col: "id", dataType: schema.Int, values: values, not: true, wantQuery: "id <> ALL(CAST(? AS bigint[]))", wantArgs: 1},
+		{name: "postgres text of integers", driver: PostgresSQL, col: "code", dataType: schema.String, values: values, wantQuery: "code = ANY(CAST(? AS text[]))", wantArgs: 1},
+		{name: "postgres unknown type", driver: PostgresSQL, col: "code", values: values, wantQuery: "CAST(code AS text) = ANY(CAST(? AS text[]))", wantArgs: 1},
+		{name: "sqlserver", driver: SQLServer, col: "id", dataType: schema.Int, values: values, wantQuery: "id IN (SELECT value FROM OPENJSON(?))", wantArgs: 1},
+		{name: "mysql chunks", driver: MySQL, col: "id", dataType: schema.Int, values: values, not: true, wantQuery: "(id NOT IN (?) AND id NOT IN (?) AND id NOT IN (?))", wantArgs: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := inListCondition(tt.driver, tt.col, tt.dataType, tt.not, tt.values)
+			if query != tt.wantQuery || len(args) != tt.wantArgs {
+				t.Errorf("inListCondition() = %s, %d args, want %s, %d args", query, len(args), tt.wantQuery, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_postgresArray_Value(t *testing.T) {
+	got, _ := postgresArray{`a"b`, `c\d`, "e,f"}.Value()
+	if want := `{"a\"b","c\\d","e,f"}`; got != want {
+		t.Errorf("postgresArray.Value() = %v, want %v", got, want)
+	}
+}
@@ -14,8 +14,10 @@ import (
 )
 
 const (
-	// Secret ...
-	Secret string = "SecretKey" // replace SecretKey with strong key
+	// Secret is a placeholder which is rejected as the secret of AuthKey
+	//
+	// Deprecated: configure the keys of AuthConfig.
+	Secret string = "SecretKey"
 	// JWT Claims -----------------------------------------------------------------------
 	// ClaimsClientKey ...
 	ClaimsClientKey string = "key"
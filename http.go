@@ -162,11 +162,16 @@ func ParsePaginationParams(ctx echo.Context) (limit int, offset int, err error)
 	return int(limit), int(offset), err
 }
 
+// ParseContext returns the query params parsed by ParseURL, the defaults are returned if ParseURL did not run
 func ParseContext(ctx echo.Context) (limit, offset int, filters map[string][]FilterValue, sorts []SortValue) {
-	limit = ctx.Get(CTXLimit).(int)
-	offset = ctx.Get(CTXOffset).(int)
-	filters = ctx.Get(CTXFilters).(map[string][]FilterValue)
-	sorts = ctx.Get(CTXSorts).([]SortValue)
+	limit, _ = ctx.Get(CTXLimit).(int)
+	offset, _ = ctx.Get(CTXOffset).(int)
+	filters, _ = ctx.Get(CTXFilters).(map[string][]FilterValue)
+	sorts, _ = ctx.Get(CTXSorts).([]SortValue)
+
+	if filters == nil {
+		filters = make(map[string][]FilterValue)
+	}
 
 	if limit <= 0 {
 		limit = 5
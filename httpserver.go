@@ -2,14 +2,20 @@ package simutils
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/alifakhimi/simple-utils-go/multierror"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
@@ -23,8 +29,10 @@ type (
 	HttpServer struct {
 		HttpServerConfig
 		// echo is an instance of echo.labstack.com
-		echo        *echo.Echo
-		prefixGroup *echo.Group
+		echo          *echo.Echo
+		prefixGroup   *echo.Group
+		authenticator *Authenticator
+		authorizer    *Authorizer
 	}
 
 	HttpServerLogLevel uint8
@@ -42,9 +50,33 @@ type (
 		Debug bool `json:"debug,omitempty"`
 		// LogLevel
 		LogLevel HttpServerLogLevel `json:"log_level,omitempty"`
+		// Middlewares are used in order instead of the default logger/recover middleware,
+		// see BuildMiddlewares for the available middlewares
+		Middlewares []MiddlewareConfig `json:"middlewares,omitempty"`
+		// TLS serves https when it is set
+		TLS *TLSConfig `json:"tls,omitempty"`
+		// ShutdownTimeout is the time of draining connections on shutdown,
+		// DefaultShutdownTimeout is used if it is not set
+		ShutdownTimeout Duration `json:"shutdown_timeout,omitempty"`
+		// ErrorFormat is the format of error responses, template by default.
+		// Requests which accept application/problem+json get problem details in any format.
+		ErrorFormat ErrorFormat `json:"error_format,omitempty"`
+		// Auth issues and verifies JWTs and protects the routes of its config
+		Auth *AuthConfig `json:"auth,omitempty"`
+		// Policy authorizes the roles of authenticated users, see Authorizer
+		Policy *Policy `json:"policy,omitempty"`
+	}
+
+	// HttpServerError is the error of a server of HttpServers
+	HttpServerError struct {
+		Server string
+		Err    error
 	}
 )
 
+// DefaultShutdownTimeout is the shutdown timeout of servers which have no ShutdownTimeout
+var DefaultShutdownTimeout = 10 * time.Second
+
 const (
 	DEBUG HttpServerLogLevel = iota + 1
 	INFO
@@ -53,6 +85,14 @@ const (
 	OFF
 )
 
+func (e *HttpServerError) Error() string {
+	return fmt.Sprintf("%s service: %v", e.Server, e.Err)
+}
+
+func (e *HttpServerError) Unwrap() error {
+	return e.Err
+}
+
 func (h *HttpServer) Echo() *echo.Echo {
 	return h.echo
 }
@@ -61,14 +101,63 @@ func (h *HttpServer) PrefixGroup() *echo.Group {
 	return h.prefixGroup
 }
 
+// Authenticator returns the authenticator of Auth, it is nil if Auth is not set
+func (h *HttpServer) Authenticator() *Authenticator {
+	return h.authenticator
+}
+
+// Authorizer returns the authorizer of Policy, it is nil if Policy is not set.
+// Policies of database are loaded by LoadPolicy and SetPolicy.
+func (h *HttpServer) Authorizer() *Authorizer {
+	return h.authorizer
+}
+
+// AuthGroup returns a group of the prefix group whose requests are authenticated,
+// users must have one of roles if they are given. All requests are unauthorized if Auth is not set.
+func (h *HttpServer) AuthGroup(prefix string, roles ...string) *echo.Group {
+	if h.authenticator == nil {
+		return h.prefixGroup.Group(prefix, func(echo.HandlerFunc) echo.HandlerFunc {
+			return func(echo.Context) error { return ErrUnauthorized }
+		})
+	}
+
+	return h.prefixGroup.Group(prefix, h.authenticator.Middleware(roles...))
+}
+
 func (h *HttpServer) newEcho() (err error) {
 	// Create echo instance
 	h.echo = echo.New()
 	h.echo.HideBanner = true
 	h.echo.Logger.SetLevel(log.Lvl(h.LogLevel))
+	h.echo.HTTPErrorHandler = HTTPErrorHandler(h.Debug)
+	if h.ErrorFormat != "" {
+		h.echo.Pre(ErrorFormatMiddleware(h.ErrorFormat))
+	}
+
+	// Wrap requests in *Context, it must run after routing so it is not a Pre middleware
+	h.echo.Use(ContextMiddleware())
+
+	if h.Auth != nil {
+		if h.authenticator, err = NewAuthenticator(*h.Auth, nil); err != nil {
+			return err
+		}
+		if len(h.Auth.Routes) > 0 {
+			h.echo.Use(h.authenticator.RoutesMiddleware())
+		}
+	}
+
+	if h.Policy != nil {
+		h.authorizer = NewAuthorizer(*h.Policy)
+	}
 
 	// Create service middleware logger/recover related to Debug
-	if h.Debug {
+	if len(h.Middlewares) > 0 {
+		mws, err := BuildMiddlewares(h.Middlewares)
+		if err != nil {
+			return err
+		}
+		h.echo.Use(mws...)
+	} else if h.Debug {
 		h.echo.Use(middleware.Logger())
 	} else {
 		h.echo.Use(middleware.Recover())
@@ -77,7 +166,7 @@ func (h *HttpServer) newEcho() (err error) {
 	h.prefixGroup = h.echo.Group(h.Prefix)
 
 	// API Doc
-	h.prefixGroup.GET("/swagger/*", echoSwagger.WrapHandler)
+	h.prefixGroup.GET("/swagger/*", echoSwagger.EchoWrapHandler(echoSwagger.InstanceName(SwaggerInstanceName)))
 
 	// Add default routes
 	h.prefixGroup.Any("/healthinfo", func(ctx echo.Context) error {
@@ -103,57 +192,211 @@ func (h *HttpServer) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	tmp.newEcho()
+	if err := tmp.newEcho(); err != nil {
+		return err
+	}
 
 	*h = *tmp
 
 	return nil
 }
 
+// Run starts the server and blocks until it is shut down
 func (h *HttpServer) Run() error {
+	if err := h.listen(); err != nil {
+		return err
+	}
+
+	return h.serve()
+}
+
+// server returns the http server of echo, TLSServer if TLS is set
+func (h *HttpServer) server() *http.Server {
+	if h.TLS != nil {
+		return h.echo.TLSServer
+	}
+
+	return h.echo.Server
+}
+
+// listen binds the address of server, so the server is ready to accept connections before serve
+func (h *HttpServer) listen() error {
 	if h.echo == nil {
 		if err := h.newEcho(); err != nil {
 			return err
 		}
 	}
 
-	if err := h.echo.Start(h.Address); err != http.ErrServerClosed {
+	s := h.server()
+	s.Addr = h.Address
+
+	if h.TLS != nil {
+		if err := h.TLS.configureServer(s); err != nil {
+			return err
+		}
+	}
+
+	address := h.Address
+	if address == "" {
+		address = ":http"
+		if h.TLS != nil {
+			address = ":https"
+		}
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
 		return err
 	}
 
+	if h.TLS != nil {
+		h.echo.TLSListener = tls.NewListener(l, s.TLSConfig)
+	} else {
+		h.echo.Listener = l
+	}
+
 	return nil
 }
 
-func (hs HttpServers) RunAll() error {
-	var err error
+// closeListener closes the listener of a server which is not served
+func (h *HttpServer) closeListener() {
+	if h.echo.TLSListener != nil {
+		_ = h.echo.TLSListener.Close()
+	}
+	if h.echo.Listener != nil {
+		_ = h.echo.Listener.Close()
+	}
+}
+
+func (h *HttpServer) serve() error {
+	if err := h.echo.StartServer(h.server()); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// shutdown gracefully shuts down the server in ShutdownTimeout
+func (h *HttpServer) shutdown() error {
+	timeout := h.ShutdownTimeout.Duration
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := h.server().Shutdown(ctx); err != nil {
+		// drop the connections which are not drained
+		_ = h.server().Close()
+		return err
+	}
+
+	return nil
+}
+
+// RunAll runs the servers until SIGINT or SIGTERM, see RunAllContext
+func (hs HttpServers) RunAll() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	for k, h := range hs {
-		go func(k string, h *HttpServer) {
-			fmt.Printf("%s service start \n", k)
-			defer fmt.Printf("%s service stopped \n", k)
+	return hs.RunAllContext(ctx)
+}
+
+// RunAllContext binds the addresses of all servers before serving any of them,
+// so nothing is served if an address can not be bound.
+// The servers are shut down when ctx is done or a server fails, every server drains
+// its connections in its ShutdownTimeout. The result is a multierror of HttpServerError.
+func (hs HttpServers) RunAllContext(ctx context.Context) error {
+	names := make([]string, 0, len(hs))
+	for name := range hs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-			if e := h.Run(); e != nil {
-				err = errors.Join(e)
+	var errs []error
+
+	// readiness barrier
+	for _, name := range names {
+		if err := hs[name].listen(); err != nil {
+			errs = append(errs, &HttpServerError{Server: name, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		for _, name := range names {
+			if hs[name].echo != nil {
+				hs[name].closeListener()
 			}
-		}(k, h)
+		}
+		return multierror.Join(errs...)
+	}
+
+	type result struct {
+		name string
+		err  error
 	}
 
-	// Wait for interrupt signal to gracefully shutdown the server with a timeout of 10 seconds.
-	<-ctx.Done()
+	results := make(chan result, len(names))
+	for _, name := range names {
+		go func(name string, h *HttpServer) {
+			fmt.Printf("%s service start \n", name)
+			results <- result{name: name, err: h.serve()}
+		}(name, hs[name])
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	var (
+		done    = 0
+		outcome = map[string]error{}
+	)
+
+	select {
+	case <-ctx.Done():
+	case r := <-results:
+		// a server failed, stop the others
+		done++
+		outcome[r.name] = r.err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		shutdowns = map[string]error{}
+	)
 
-	for k, h := range hs {
-		fmt.Printf("%s service is shutting down \n", k)
-		if e := h.echo.Shutdown(ctx); e != nil {
-			err = errors.Join(err, e)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string, h *HttpServer) {
+			defer wg.Done()
+
+			fmt.Printf("%s service is shutting down \n", name)
+			err := h.shutdown()
+
+			mu.Lock()
+			shutdowns[name] = err
+			mu.Unlock()
+		}(name, hs[name])
+	}
+	wg.Wait()
+
+	for ; done < len(names); done++ {
+		r := <-results
+		outcome[r.name] = r.err
+	}
+
+	for _, name := range names {
+		if err := outcome[name]; err != nil {
+			errs = append(errs, &HttpServerError{Server: name, Err: err})
 		}
-		fmt.Printf("%s service gracefully shutdown \n", k)
+		if err := shutdowns[name]; err != nil {
+			errs = append(errs, &HttpServerError{Server: name, Err: fmt.Errorf("shutdown: %w", err)})
+		}
+		fmt.Printf("%s service stopped \n", name)
 	}
 
-	return err
+	if err := multierror.Join(errs...); err != nil {
+		return err
+	}
+
+	return nil
 }
@@ -0,0 +1,128 @@
+package simutils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// freeTestAddress returns a free address of localhost
+func freeTestAddress(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+// newTestHttpServers returns servers which listen on free ports of localhost
+func newTestHttpServers(t *testing.T, names ...string) HttpServers {
+	t.Helper()
+
+	hs := HttpServers{}
+	for _, name := range names {
+		h := &HttpServer{HttpServerConfig: HttpServerConfig{Address: freeTestAddress(t)}}
+		if err := h.newEcho(); err != nil {
+			t.Fatal(err)
+		}
+		h.echo.HidePort = true
+		hs[name] = h
+	}
+
+	return hs
+}
+
+// waitHttpServer waits until url is served
+func waitHttpServer(t *testing.T, client *http.Client, url string) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if resp, err := client.Get(url); err == nil {
+			resp.Body.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("%s is not served", url)
+}
+
+func TestHttpServers_RunAllContext(t *testing.T) {
+	t.Run("graceful shutdown", func(t *testing.T) {
+		hs := newTestHttpServers(t, "api", "admin")
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+
+		go func() { errCh <- hs.RunAllContext(ctx) }()
+		waitHttpServer(t, http.DefaultClient, "http://"+hs["api"].Address+"/healthinfo")
+		waitHttpServer(t, http.DefaultClient, "http://"+hs["admin"].Address+"/healthinfo")
+		cancel()
+
+		if err := <-errCh; err != nil {
+			t.Errorf("RunAllContext() error = %v", err)
+		}
+	})
+
+	t.Run("bind failure", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		hs := newTestHttpServers(t, "api", "admin")
+		hs["admin"].Address = l.Addr().String()
+
+		err = hs.RunAllContext(context.Background())
+
+		var serverErr *HttpServerError
+		if !errors.As(err, &serverErr) || serverErr.Server != "admin" {
+			t.Fatalf("RunAllContext() error = %v, want admin service error", err)
+		}
+
+		// the listener of api is closed
+		if conn, err := net.Dial("tcp", hs["api"].Address); err == nil {
+			conn.Close()
+			t.Errorf("api service is listening on %s", hs["api"].Address)
+		}
+	})
+
+	t.Run("drain timeout", func(t *testing.T) {
+		hs := newTestHttpServers(t, "api")
+		hs["api"].ShutdownTimeout = Duration{50 * time.Millisecond}
+
+		started := make(chan struct{})
+		hs["api"].echo.GET("/slow", func(ctx echo.Context) error {
+			close(started)
+			time.Sleep(time.Second)
+			return ctx.NoContent(http.StatusOK)
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+
+		go func() { errCh <- hs.RunAllContext(ctx) }()
+		waitHttpServer(t, http.DefaultClient, "http://"+hs["api"].Address+"/healthinfo")
+
+		go func() {
+			if resp, err := http.Get("http://" + hs["api"].Address + "/slow"); err == nil {
+				resp.Body.Close()
+			}
+		}()
+		<-started
+		cancel()
+
+		if err := <-errCh; !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("RunAllContext() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
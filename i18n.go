@@ -0,0 +1,118 @@
+package simutils
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is the language of requests which accept no language of catalogs
+var DefaultLanguage = "en"
+
+//go:embed i18n/*.json
+var catalogFS embed.FS
+
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = map[string]map[string]string{}
+	langs      []string
+	matcher    language.Matcher
+)
+
+func init() {
+	entries, err := catalogFS.ReadDir("i18n")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range entries {
+		b, err := catalogFS.ReadFile(path.Join("i18n", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			panic(err)
+		}
+
+		RegisterCatalog(strings.TrimSuffix(entry.Name(), ".json"), messages)
+	}
+}
+
+// RegisterCatalog adds the translations of message keys in lang like fa or en,
+// existing keys are replaced
+func RegisterCatalog(lang string, messages map[string]string) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+
+	catalog, ok := catalogs[lang]
+	if !ok {
+		catalog = map[string]string{}
+		catalogs[lang] = catalog
+	}
+
+	for k, v := range messages {
+		catalog[k] = v
+	}
+
+	langs = langs[:0]
+	for l := range catalogs {
+		langs = append(langs, l)
+	}
+
+	// the first language is the fallback of matcher
+	sort.Slice(langs, func(i, j int) bool {
+		if langs[i] == DefaultLanguage || langs[j] == DefaultLanguage {
+			return langs[i] == DefaultLanguage
+		}
+		return langs[i] < langs[j]
+	})
+
+	tags := make([]language.Tag, 0, len(langs))
+	for _, l := range langs {
+		tags = append(tags, language.Make(l))
+	}
+	matcher = language.NewMatcher(tags)
+}
+
+// Translate returns the message of key in lang with {name} placeholders replaced by details
+func Translate(lang, key string, details map[string]any) (string, bool) {
+	catalogsMu.RLock()
+	msg, ok := catalogs[lang][key]
+	catalogsMu.RUnlock()
+
+	if !ok {
+		return "", false
+	}
+
+	return interpolate(msg, details), true
+}
+
+// AcceptLanguage returns the best language of catalogs for the Accept-Language header of r
+func AcceptLanguage(r *http.Request) string {
+	header := r.Header.Get("Accept-Language")
+	if header == "" {
+		return DefaultLanguage
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 {
+		return DefaultLanguage
+	}
+
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+
+	if _, i, conf := matcher.Match(tags...); conf != language.No {
+		return langs[i]
+	}
+
+	return DefaultLanguage
+}
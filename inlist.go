@@ -0,0 +1,176 @@
+package simutils
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// LargeInListThreshold is the number of values after which an `in` or `nin` filter
+	// is sent as a single array parameter instead of one parameter per value
+	LargeInListThreshold = 2000
+	// InListChunkSize is the number of values of every IN predicate
+	// on drivers without array parameters
+	InListChunkSize = 1000
+)
+
+// inListCondition builds the condition of an `in` or `nin` filter on col of dataType.
+// Large lists are passed as a postgres array, a json array expanded by json_each
+// on sqlite and OPENJSON on sqlserver, or chunked into OR-ed IN predicates otherwise.
+func inListCondition(driver DatabaseDriver, col string, dataType schema.DataType, not bool, values []string) (string, []interface{}) {
+	if len(values) <= LargeInListThreshold {
+		if not {
+			return fmt.Sprintf("%s NOT IN (?)", col), []interface{}{values}
+		}
+		return fmt.Sprintf("%s IN (?)", col), []interface{}{values}
+	}
+
+	switch driver {
+	case PostgresSQL:
+		typ, ok := postgresArrayType(dataType)
+		if !ok {
+			col = fmt.Sprintf("CAST(%s AS text)", col)
+		}
+
+		if not {
+			return fmt.Sprintf("%s <> ALL(CAST(? AS %s))", col, typ), []interface{}{postgresArray(values)}
+		}
+		return fmt.Sprintf("%s = ANY(CAST(? AS %s))", col, typ), []interface{}{postgresArray(values)}
+	case SQLite, SQLServer:
+		list := "SELECT value FROM json_each(?)"
+		if driver == SQLServer {
+			list = "SELECT value FROM OPENJSON(?)"
+		}
+
+		if not {
+			return fmt.Sprintf("%s NOT IN (%s)", col, list), []interface{}{jsonArray(values)}
+		}
+		return fmt.Sprintf("%s IN (%s)", col, list), []interface{}{jsonArray(values)}
+	}
+
+	var (
+		query []string
+		args  []interface{}
+		op    = "IN"
+		join  = " OR "
+	)
+
+	if not {
+		op, join = "NOT IN", " AND "
+	}
+
+	for offset := 0; offset < len(values); offset += InListChunkSize {
+		end := offset + InListChunkSize
+		if end > len(values) {
+			end = len(values)
+		}
+
+		query = append(query, fmt.Sprintf("%s %s (?)", col, op))
+		args = append(args, values[offset:end])
+	}
+
+	return "(" + strings.Join(query, join) + ")", args
+}
+
+// columnDataTypesOf maps the columns of the model of db to their data types,
+// nil is returned if db has no model
+func columnDataTypesOf(db *gorm.DB) map[string]schema.DataType {
+	if db.Statement.Model == nil {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(db.Statement.Model); err != nil {
+		return nil
+	}
+
+	dataTypes := map[string]schema.DataType{}
+	for _, f := range stmt.Schema.Fields {
+		if f.DBName != "" {
+			dataTypes[f.DBName] = f.DataType
+		}
+	}
+
+	return dataTypes
+}
+
+// columnDataType returns the data type of col which may be prefixed by its table
+func columnDataType(dataTypes map[string]schema.DataType, col string) schema.DataType {
+	if i := strings.LastIndex(col, "."); i >= 0 {
+		col = col[i+1:]
+	}
+
+	return dataTypes[col]
+}
+
+// isIntegerList reports whether all values are integers
+func isIntegerList(values []string) bool {
+	for _, v := range values {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// postgresArrayType returns the array type of the values of a column of dataType,
+// columns of other types are not of the array type and must be cast to text
+func postgresArrayType(dataType schema.DataType) (string, bool) {
+	switch dataType {
+	case schema.Int, schema.Uint:
+		return "bigint[]", true
+	case schema.Float:
+		return "double precision[]", true
+	case schema.Bool:
+		return "boolean[]", true
+	case schema.Time:
+		return "timestamptz[]", true
+	case schema.String:
+		return "text[]", true
+	}
+
+	return "text[]", false
+}
+
+// postgresArray is a postgres array literal of values
+type postgresArray []string
+
+// Value implements driver.Valuer interface
+func (a postgresArray) Value() (driver.Value, error) {
+	elems := make([]string, len(a))
+	for i, v := range a {
+		elems[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+	}
+
+	return "{" + strings.Join(elems, ",") + "}", nil
+}
+
+// jsonArray is a json array of values, numbers are kept as json numbers
+// to be compared with numeric columns
+type jsonArray []string
+
+// Value implements driver.Valuer interface
+func (a jsonArray) Value() (driver.Value, error) {
+	var values interface{} = []string(a)
+	if isIntegerList(a) {
+		numbers := make([]int64, len(a))
+		for i, v := range a {
+			numbers[i], _ = strconv.ParseInt(v, 10, 64)
+		}
+		values = numbers
+	}
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,292 @@
+package simutils
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// CountStrategy defines how List computes the total number of records
+type CountStrategy int
+
+const (
+	// CountSeparate runs the count query and then the page query
+	CountSeparate CountStrategy = iota
+	// CountConcurrent runs the count and page queries at the same time
+	CountConcurrent
+	// CountWindow reads the total from a COUNT(*) OVER() column of the page query
+	CountWindow
+)
+
+// windowTotalColumn is the alias of the window count column
+const windowTotalColumn = "list_window_total"
+
+// ListSpec describes how List maps query params to a model
+type ListSpec struct {
+	// Driver is used to correct similar chars in filter values,
+	// if it is zero the driver of db is used
+	Driver DatabaseDriver
+	// Columns maps filter and sort keys to table columns
+	Columns map[string][]string
+	// Includes is the list of associations allowed in `includes` query param
+	Includes []string
+	// Select limits the columns returned by the page query
+	Select []string
+	// Count is the strategy of counting total records
+	Count CountStrategy
+	// MaxLimit is the maximum page size, 100 if it is zero
+	MaxLimit int
+	// Scopes are applied to both count and page queries
+	Scopes []func(*gorm.DB) *gorm.DB
+}
+
+// List runs a paginated query on T using the filters, sorts, limit and offset
+// of the request and returns the page wrapped in a ResponseTemplate.
+// ParseURL is called if it did not run before.
+func List[T any](ctx echo.Context, db *gorm.DB, spec ListSpec) (*ResponseTemplate, error) {
+	var (
+		items []T
+		total int64
+	)
+
+	if ctx.Get(CTXFilters) == nil {
+		if err := ParseURL(ctx); err != nil {
+			return nil, ErrInvalidRequest
+		}
+	}
+
+	limit, offset, filters, sorts := ParseContext(ctx)
+	if maxLimit := DefaultIfZero(spec.MaxLimit, 100); limit > maxLimit {
+		limit = maxLimit
+	}
+
+	includes, err := parseIncludes(ctx, spec.Includes)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := parseListFields[T](ctx, db, spec, includes)
+	if err != nil {
+		return nil, err
+	}
+
+	driver := spec.Driver
+	if driver == 0 {
+		driver = DriverOf(db)
+	}
+
+	base := db.WithContext(ctx.Request().Context()).Model(new(T)).Scopes(spec.Scopes...)
+	if base, err = ParseFilters(base, driver, filters, spec.Columns); err != nil {
+		return nil, err
+	}
+
+	// full text search on fields tagged with `search`
+	s, err := buildSearch(db, new(T), ctx.QueryParam("q"))
+	if err != nil {
+		return nil, ErrInvalidRequest
+	} else if s != nil {
+		base = base.Where(s.where)
+	}
+	base = base.Session(&gorm.Session{})
+
+	page := base
+	if page, err = ParseSorts(page, sorts, spec.Columns); err != nil {
+		return nil, err
+	}
+	if s != nil {
+		// explicit sorts come before relevance
+		page = orderBySearch(page, s.order)
+	}
+	if len(spec.Select) > 0 {
+		page = page.Select(spec.Select)
+	}
+	for _, inc := range includes {
+		page = page.Preload(inc)
+	}
+	if fields != nil {
+		page = fields.Apply(page)
+	}
+	page = page.Limit(limit).Offset(offset)
+
+	strategy := spec.Count
+	if strategy == CountWindow && len(includes) > 0 {
+		// preloads do not run on raw rows
+		strategy = CountConcurrent
+	}
+
+	switch strategy {
+	case CountConcurrent:
+		var (
+			wg       sync.WaitGroup
+			countErr error
+		)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			countErr = base.Count(&total).Error
+		}()
+
+		err = page.Find(&items).Error
+		wg.Wait()
+
+		if err == nil {
+			err = countErr
+		}
+	case CountWindow:
+		if items, total, err = findWithWindowCount[T](page); err == nil && len(items) == 0 && offset > 0 {
+			// the window is empty when offset is out of range
+			err = base.Count(&total).Error
+		}
+	default:
+		if err = base.Count(&total).Error; err == nil {
+			err = page.Find(&items).Error
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+
+	paginate := CreatePaginateTemplate(int(total), offset, limit)
+	paginate.Count = len(items)
+
+	if fields != nil {
+		data, err := fields.Filter(items)
+		if err != nil {
+			return nil, err
+		}
+		return ResponseOk(data, nil, paginate), nil
+	}
+
+	return ResponseOk(items, nil, paginate), nil
+}
+
+// parseIncludes returns the requested associations of `includes` query param,
+// every association must exist in allowed list
+func parseIncludes(ctx echo.Context, allowed []string) (includes []string, err error) {
+	for _, v := range ctx.QueryParams()["includes"] {
+		for _, inc := range strings.Split(v, ",") {
+			if inc = strings.TrimSpace(inc); inc == "" {
+				continue
+			}
+
+			if !ArrayElementExists(allowed, inc) {
+				return nil, ErrInvalidRequest
+			}
+
+			includes = append(includes, inc)
+		}
+	}
+
+	return includes, nil
+}
+
+// parseListFields parses `fields` query param, the associations of fields
+// must be included and the columns must be in spec.Select if it is set
+func parseListFields[T any](ctx echo.Context, db *gorm.DB, spec ListSpec, includes []string) (*FieldSet, error) {
+	raw := ctx.QueryParam("fields")
+	if raw == "" {
+		return nil, nil
+	}
+
+	fields, err := ParseFieldSet(db, new(T), raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range fields.Associations() {
+		if !ArrayElementExists(includes, name) {
+			return nil, ErrInvalidRequest
+		}
+	}
+
+	if len(spec.Select) > 0 {
+		for _, col := range fields.Columns {
+			if !ArrayElementExists(spec.Select, col) {
+				return nil, ErrInvalidRequest
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// findWithWindowCount runs the page query with a COUNT(*) OVER() column
+// and scans rows into T while reading the total from the extra column
+func findWithWindowCount[T any](page *gorm.DB) (items []T, total int64, err error) {
+	stmt := &gorm.Statement{DB: page}
+	if err = stmt.Parse(new(T)); err != nil {
+		return nil, 0, err
+	}
+
+	selects := []string{stmt.Quote(stmt.Schema.Table) + ".*"}
+	if len(page.Statement.Selects) > 0 {
+		selects = page.Statement.Selects
+	}
+
+	rows, err := page.Select(fmt.Sprintf("%s, COUNT(*) OVER() AS %s", strings.Join(selects, ", "), windowTotalColumn)).Rows()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fields := make([]*schema.Field, len(columns))
+	for idx, col := range columns {
+		if f := stmt.Schema.LookUpField(col); f != nil && f.Readable {
+			fields[idx] = f
+		}
+	}
+
+	for rows.Next() {
+		var (
+			item   T
+			values = make([]interface{}, len(columns))
+			rv     = reflect.ValueOf(&item).Elem()
+		)
+
+		for idx, col := range columns {
+			switch {
+			case col == windowTotalColumn:
+				values[idx] = &total
+			case fields[idx] != nil:
+				values[idx] = fields[idx].NewValuePool.Get()
+			default:
+				values[idx] = new(interface{})
+			}
+		}
+
+		if err = rows.Scan(values...); err != nil {
+			return nil, 0, err
+		}
+
+		for idx, f := range fields {
+			if f == nil {
+				continue
+			}
+
+			if err = f.Set(page.Statement.Context, rv, values[idx]); err != nil {
+				return nil, 0, err
+			}
+			f.NewValuePool.Put(values[idx])
+		}
+
+		items = append(items, item)
+	}
+
+	return items, total, rows.Err()
+}
@@ -0,0 +1,138 @@
+package simutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type listTestOwner struct {
+	Model
+	Name string
+}
+
+type listTestItem struct {
+	Model
+	Name    string
+	Price   int
+	OwnerID PID
+	Owner   *listTestOwner
+}
+
+func newListTestContext(query string) echo.Context {
+	req := httptest.NewRequest(http.MethodGet, "/items?"+query, nil)
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestList(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:list_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+
+	if err := db.AutoMigrate(&listTestOwner{}, &listTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := listTestOwner{Model: Model{ID: 1}, Name: "owner"}
+	db.Create(&owner)
+	for i := 1; i <= 12; i++ {
+		db.Create(&listTestItem{Model: Model{ID: PID(i)}, Name: "item", Price: i, OwnerID: owner.ID})
+	}
+
+	spec := ListSpec{
+		Columns:  map[string][]string{"price": {"price"}, "name": {"name"}},
+		Includes: []string{"Owner"},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		count     CountStrategy
+		wantTotal int
+		wantCount int
+		wantFirst int
+		wantErr   bool
+	}{
+		{name: "separate count", query: "limit=5&sort=price:desc", wantTotal: 12, wantCount: 5, wantFirst: 12},
+		{name: "concurrent count with filter", query: "limit=5&price=gt:8", count: CountConcurrent, wantTotal: 4, wantCount: 4, wantFirst: 9},
+		{name: "window count", query: "limit=5&offset=10&sort=price", count: CountWindow, wantTotal: 12, wantCount: 2, wantFirst: 11},
+		{name: "window count out of range", query: "limit=5&offset=20", count: CountWindow, wantTotal: 12, wantCount: 0},
+		{name: "includes", query: "limit=1&includes=Owner", count: CountWindow, wantTotal: 12, wantCount: 1, wantFirst: 1},
+		{name: "includes not allowed", query: "includes=Secret", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := spec
+			s.Count = tt.count
+
+			got, err := List[listTestItem](newListTestContext(tt.query), db, s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("List() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			items := got.Data.([]listTestItem)
+			paginate := got.Meta.(*PaginateTemplate)
+			if paginate.Total != tt.wantTotal || paginate.Count != tt.wantCount || len(items) != tt.wantCount {
+				t.Errorf("List() total = %d, count = %d, want %d, %d", paginate.Total, paginate.Count, tt.wantTotal, tt.wantCount)
+			}
+			if len(items) > 0 && items[0].Price != tt.wantFirst {
+				t.Errorf("List() first price = %d, want %d", items[0].Price, tt.wantFirst)
+			}
+			if tt.query == "limit=1&includes=Owner" && (len(items) == 0 || items[0].Owner == nil) {
+				t.Errorf("List() owner is not preloaded")
+			}
+		})
+	}
+}
+
+func TestList_Fields(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:list_fields_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+
+	if err := db.AutoMigrate(&listTestOwner{}, &listTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&listTestOwner{Model: Model{ID: 1}, Name: "owner"})
+	db.Create(&listTestItem{Model: Model{ID: 1}, Name: "item", Price: 10, OwnerID: 1})
+
+	spec := ListSpec{Includes: []string{"Owner"}}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    map[string]any
+		wantErr bool
+	}{
+		{name: "root fields", query: "fields=id,price", want: map[string]any{"id": float64(1), "Price": float64(10)}},
+		{name: "association fields", query: "fields=name,owner.name&includes=Owner", want: map[string]any{"Name": "item", "Owner": map[string]any{"Name": "owner"}}},
+		{name: "association is not included", query: "fields=owner.name", wantErr: true},
+		{name: "unknown field", query: "fields=secret", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := List[listTestItem](newListTestContext(tt.query), db, spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("List() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			items := got.Data.([]any)
+			if len(items) != 1 || !reflect.DeepEqual(items[0], tt.want) {
+				t.Errorf("List() data = %v, want %v", items, tt.want)
+			}
+		})
+	}
+}
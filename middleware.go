@@ -0,0 +1,267 @@
+package simutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+var (
+	ErrMiddlewareNotFound = errors.New("middleware not found")
+)
+
+type (
+	// MiddlewareConfig is a middleware of HttpServerConfig.Middlewares with its options, like
+	//
+	//	{"name": "cors", "options": {"allow_origins": ["https://example.com"]}}
+	MiddlewareConfig struct {
+		Name    string `json:"name"`
+		Options JSON   `json:"options,omitempty"`
+	}
+
+	// MiddlewareFactory builds a middleware from its json options, options are empty if they are not set
+	MiddlewareFactory func(options JSON) (echo.MiddlewareFunc, error)
+
+	// CORSOptions are the options of cors middleware
+	CORSOptions struct {
+		AllowOrigins     []string `json:"allow_origins,omitempty"`
+		AllowMethods     []string `json:"allow_methods,omitempty"`
+		AllowHeaders     []string `json:"allow_headers,omitempty"`
+		AllowCredentials bool     `json:"allow_credentials,omitempty"`
+		ExposeHeaders    []string `json:"expose_headers,omitempty"`
+		MaxAge           int      `json:"max_age,omitempty"`
+	}
+
+	// GzipOptions are the options of gzip middleware
+	GzipOptions struct {
+		Level     int `json:"level,omitempty"`
+		MinLength int `json:"min_length,omitempty"`
+	}
+
+	// BodyLimitOptions are the options of body_limit middleware
+	BodyLimitOptions struct {
+		// Limit is the maximum size of request body like 4KB, 2M or 1G
+		Limit string `json:"limit"`
+	}
+
+	// TimeoutOptions are the options of timeout middleware
+	TimeoutOptions struct {
+		Timeout      Duration `json:"timeout"`
+		ErrorMessage string   `json:"error_message,omitempty"`
+	}
+
+	// SecureOptions are the options of secure middleware
+	SecureOptions struct {
+		XSSProtection         string `json:"xss_protection,omitempty"`
+		ContentTypeNosniff    string `json:"content_type_nosniff,omitempty"`
+		XFrameOptions         string `json:"x_frame_options,omitempty"`
+		HSTSMaxAge            int    `json:"hsts_max_age,omitempty"`
+		ContentSecurityPolicy string `json:"content_security_policy,omitempty"`
+		ReferrerPolicy        string `json:"referrer_policy,omitempty"`
+	}
+
+	// RateLimitOptions are the options of rate_limit middleware, requests are limited per client IP
+	RateLimitOptions struct {
+		// Rate is the number of requests per second
+		Rate float64 `json:"rate"`
+		// Burst is the number of requests at once
+		Burst int `json:"burst,omitempty"`
+		// ExpiresIn is the duration of keeping an idle client
+		ExpiresIn Duration `json:"expires_in,omitempty"`
+	}
+
+	// CSRFOptions are the options of csrf middleware
+	CSRFOptions struct {
+		TokenLookup    string `json:"token_lookup,omitempty"`
+		CookieName     string `json:"cookie_name,omitempty"`
+		CookieDomain   string `json:"cookie_domain,omitempty"`
+		CookiePath     string `json:"cookie_path,omitempty"`
+		CookieMaxAge   int    `json:"cookie_max_age,omitempty"`
+		CookieSecure   bool   `json:"cookie_secure,omitempty"`
+		CookieHTTPOnly bool   `json:"cookie_http_only,omitempty"`
+		// CookieSameSite is one of lax, strict and none
+		CookieSameSite string `json:"cookie_same_site,omitempty"`
+	}
+)
+
+var (
+	middlewaresMu sync.RWMutex
+	middlewares   = map[string]MiddlewareFactory{
+		"logger":     func(JSON) (echo.MiddlewareFunc, error) { return middleware.Logger(), nil },
+		"recover":    func(JSON) (echo.MiddlewareFunc, error) { return middleware.Recover(), nil },
+		"request_id": func(JSON) (echo.MiddlewareFunc, error) { return middleware.RequestID(), nil },
+		"cors":       corsMiddleware,
+		"gzip":       gzipMiddleware,
+		"body_limit": bodyLimitMiddleware,
+		"timeout":    timeoutMiddleware,
+		"secure":     secureMiddleware,
+		"rate_limit": rateLimitMiddleware,
+		"csrf":       csrfMiddleware,
+	}
+)
+
+// RegisterMiddleware registers a middleware which can be used by name in HttpServerConfig.Middlewares.
+// Middlewares must be registered before the config is loaded, an existing name is replaced.
+func RegisterMiddleware(name string, factory MiddlewareFactory) {
+	middlewaresMu.Lock()
+	defer middlewaresMu.Unlock()
+
+	middlewares[name] = factory
+}
+
+// BuildMiddlewares builds the middlewares of configs in order
+func BuildMiddlewares(configs []MiddlewareConfig) ([]echo.MiddlewareFunc, error) {
+	middlewaresMu.RLock()
+	defer middlewaresMu.RUnlock()
+
+	result := make([]echo.MiddlewareFunc, 0, len(configs))
+	for _, conf := range configs {
+		factory, ok := middlewares[conf.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMiddlewareNotFound, conf.Name)
+		}
+
+		m, err := factory(conf.Options)
+		if err != nil {
+			return nil, fmt.Errorf("middleware %s: %w", conf.Name, err)
+		}
+
+		result = append(result, m)
+	}
+
+	return result, nil
+}
+
+// decodeMiddlewareOptions decodes options into v, empty options keep the defaults of v
+func decodeMiddlewareOptions(options JSON, v any) error {
+	if len(options) == 0 || string(options) == "null" {
+		return nil
+	}
+
+	return json.Unmarshal(options, v)
+}
+
+func corsMiddleware(options JSON) (echo.MiddlewareFunc, error) {
+	var opts CORSOptions
+	if err := decodeMiddlewareOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:     opts.AllowOrigins,
+		AllowMethods:     opts.AllowMethods,
+		AllowHeaders:     opts.AllowHeaders,
+		AllowCredentials: opts.AllowCredentials,
+		ExposeHeaders:    opts.ExposeHeaders,
+		MaxAge:           opts.MaxAge,
+	}), nil
+}
+
+func gzipMiddleware(options JSON) (echo.MiddlewareFunc, error) {
+	var opts GzipOptions
+	if err := decodeMiddlewareOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	return middleware.GzipWithConfig(middleware.GzipConfig{Level: opts.Level, MinLength: opts.MinLength}), nil
+}
+
+func bodyLimitMiddleware(options JSON) (echo.MiddlewareFunc, error) {
+	var opts BodyLimitOptions
+	if err := decodeMiddlewareOptions(options, &opts); err != nil {
+		return nil, err
+	} else if opts.Limit == "" {
+		return nil, errors.New("limit is required")
+	}
+
+	return middleware.BodyLimit(opts.Limit), nil
+}
+
+func timeoutMiddleware(options JSON) (echo.MiddlewareFunc, error) {
+	var opts TimeoutOptions
+	if err := decodeMiddlewareOptions(options, &opts); err != nil {
+		return nil, err
+	} else if opts.Timeout.Duration <= 0 {
+		return nil, errors.New("timeout is required")
+	}
+
+	return middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+		Timeout:      opts.Timeout.Duration,
+		ErrorMessage: opts.ErrorMessage,
+	}), nil
+}
+
+func secureMiddleware(options JSON) (echo.MiddlewareFunc, error) {
+	conf := middleware.DefaultSecureConfig
+	opts := SecureOptions{
+		XSSProtection:      conf.XSSProtection,
+		ContentTypeNosniff: conf.ContentTypeNosniff,
+		XFrameOptions:      conf.XFrameOptions,
+	}
+	if err := decodeMiddlewareOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	conf.XSSProtection = opts.XSSProtection
+	conf.ContentTypeNosniff = opts.ContentTypeNosniff
+	conf.XFrameOptions = opts.XFrameOptions
+	conf.HSTSMaxAge = opts.HSTSMaxAge
+	conf.ContentSecurityPolicy = opts.ContentSecurityPolicy
+	conf.ReferrerPolicy = opts.ReferrerPolicy
+
+	return middleware.SecureWithConfig(conf), nil
+}
+
+func rateLimitMiddleware(options JSON) (echo.MiddlewareFunc, error) {
+	var opts RateLimitOptions
+	if err := decodeMiddlewareOptions(options, &opts); err != nil {
+		return nil, err
+	} else if opts.Rate <= 0 {
+		return nil, errors.New("rate is required")
+	}
+
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Limit(opts.Rate),
+			Burst:     opts.Burst,
+			ExpiresIn: opts.ExpiresIn.Duration,
+		}),
+		DenyHandler: func(ctx echo.Context, identifier string, err error) error {
+			return replyTemplate(ctx, http.StatusTooManyRequests, ResponseTooManyRequests(nil, http.StatusText(http.StatusTooManyRequests)))
+		},
+	}), nil
+}
+
+func csrfMiddleware(options JSON) (echo.MiddlewareFunc, error) {
+	var opts CSRFOptions
+	if err := decodeMiddlewareOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	sameSite := http.SameSiteDefaultMode
+	switch opts.CookieSameSite {
+	case "lax":
+		sameSite = http.SameSiteLaxMode
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return middleware.CSRFWithConfig(middleware.CSRFConfig{
+		TokenLookup:    opts.TokenLookup,
+		CookieName:     opts.CookieName,
+		CookieDomain:   opts.CookieDomain,
+		CookiePath:     opts.CookiePath,
+		CookieMaxAge:   opts.CookieMaxAge,
+		CookieSecure:   opts.CookieSecure,
+		CookieHTTPOnly: opts.CookieHTTPOnly,
+		CookieSameSite: sameSite,
+	}), nil
+}
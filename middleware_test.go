@@ -0,0 +1,96 @@
+package simutils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHttpServer_Middlewares(t *testing.T) {
+	RegisterMiddleware("test_header", func(options JSON) (echo.MiddlewareFunc, error) {
+		var opts struct {
+			Value string `json:"value"`
+		}
+		if err := decodeMiddlewareOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(ctx echo.Context) error {
+				ctx.Response().Header().Set("X-Test", opts.Value)
+				return next(ctx)
+			}
+		}, nil
+	})
+
+	if _, err := BuildMiddlewares([]MiddlewareConfig{{Name: "unknown"}}); !errors.Is(err, ErrMiddlewareNotFound) {
+		t.Errorf("BuildMiddlewares() error = %v, want %v", err, ErrMiddlewareNotFound)
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		headers map[string]string
+		status  int
+		wantErr bool
+	}{
+		{
+			name:    "request id and secure",
+			config:  `{"middlewares": [{"name": "request_id"}, {"name": "secure", "options": {"x_frame_options": "DENY"}}]}`,
+			headers: map[string]string{"X-Frame-Options": "DENY", "X-Content-Type-Options": "nosniff"},
+			status:  http.StatusOK,
+		},
+		{
+			name:    "custom",
+			config:  `{"middlewares": [{"name": "test_header", "options": {"value": "ok"}}]}`,
+			headers: map[string]string{"X-Test": "ok"},
+			status:  http.StatusOK,
+		},
+		{
+			name:   "rate limit",
+			config: `{"middlewares": [{"name": "rate_limit", "options": {"rate": 0.001, "burst": 1}}]}`,
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:    "unknown",
+			config:  `{"middlewares": [{"name": "unknown"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid options",
+			config:  `{"middlewares": [{"name": "body_limit", "options": {}}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h HttpServer
+			err := json.Unmarshal([]byte(tt.config), &h)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+
+			var rec *httptest.ResponseRecorder
+			// the second request is limited by rate_limit
+			for i := 0; i < 2; i++ {
+				rec = httptest.NewRecorder()
+				h.Echo().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthinfo", nil))
+			}
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			for k, v := range tt.headers {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("header %s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
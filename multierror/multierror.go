@@ -128,6 +128,20 @@ func flatten(err error) []error {
 	return []error{err}
 }
 
+// Errors returns the flattened errors
+func (m *MultiError) Errors() []error {
+	if m == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, e := range m.errors {
+		errs = append(errs, flatten(e)...)
+	}
+
+	return errs
+}
+
 func (m *MultiError) Unwrap() error {
 	if m == nil || len(m.errors) == 0 {
 		return nil
@@ -0,0 +1,189 @@
+package simutils
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// persianNormalForms maps Arabic letters and Arabic/Persian digits to their normal form
+var persianNormalForms = map[rune]rune{
+	'ي': 'ی', 'ى': 'ی', 'ئ': 'ی', 'ك': 'ک', 'ة': 'ه', 'ۀ': 'ه', 'ؤ': 'و', 'أ': 'ا', 'إ': 'ا', 'ٱ': 'ا',
+	'٠': '0', '١': '1', '٢': '2', '٣': '3', '٤': '4', '٥': '5', '٦': '6', '٧': '7', '٨': '8', '٩': '9',
+	'۰': '0', '۱': '1', '۲': '2', '۳': '3', '۴': '4', '۵': '5', '۶': '6', '۷': '7', '۸': '8', '۹': '9',
+}
+
+// NormalizePersian converts a text to its normal Persian form to be compared with plain `=` and `LIKE`:
+// Arabic letters are replaced with Persian ones, digits are converted to ASCII,
+// diacritics and tatweel are removed and zero width joiners are replaced with space.
+func NormalizePersian(s string) string {
+	var b strings.Builder
+
+	for _, ch := range s {
+		switch {
+		case ch == '‌' || ch == '‍':
+			// ZWNJ and ZWJ separate the parts of a word, like space
+			b.WriteRune(' ')
+		case ch == 'ـ' || unicode.Is(unicode.Mn, ch):
+			// tatweel and diacritics
+		default:
+			if r, ok := persianNormalForms[ch]; ok {
+				ch = r
+			}
+			b.WriteRune(ch)
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// NormalizePlugin stores the normalized text of fields tagged with `normalize:"fa"` in their shadow fields
+// on create and update. The shadow field is `<Field>Normalized` or the name after the comma,
+// like `normalize:"fa,SearchName"`.
+//
+//	type Product struct {
+//		Name           string `normalize:"fa"`
+//		NameNormalized string `gorm:"index"`
+//	}
+//
+//	db.Use(simutils.NormalizePlugin{})
+type NormalizePlugin struct{}
+
+// normalizedField is a field tagged with `normalize` and its shadow field
+type normalizedField struct {
+	field  *schema.Field
+	shadow *schema.Field
+}
+
+// normalizedFieldsCache caches normalized fields of schemas
+var normalizedFieldsCache sync.Map
+
+// Name implements gorm.Plugin interface
+func (NormalizePlugin) Name() string {
+	return "simutils:normalize"
+}
+
+// Initialize implements gorm.Plugin interface
+func (NormalizePlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("simutils:normalize", normalizeCallback); err != nil {
+		return err
+	}
+
+	return db.Callback().Update().Before("gorm:update").Register("simutils:normalize", normalizeCallback)
+}
+
+func normalizeCallback(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil {
+		return
+	}
+
+	for _, nf := range normalizedFieldsOf(stmt.Schema) {
+		if dest, ok := stmt.Dest.(map[string]interface{}); ok {
+			for _, key := range []string{nf.field.Name, nf.field.DBName} {
+				if v, ok := dest[key]; ok {
+					dest[nf.shadow.DBName] = NormalizePersian(cast.ToString(v))
+				}
+			}
+		} else {
+			nf.set(db, stmt.ReflectValue)
+			if stmt.Dest != stmt.Model {
+				// updates from another struct
+				nf.set(db, reflect.Indirect(reflect.ValueOf(stmt.Dest)))
+			}
+		}
+
+		// shadow is updated with its field
+		if len(stmt.Selects) > 0 && (ArrayElementExists(stmt.Selects, nf.field.Name) || ArrayElementExists(stmt.Selects, nf.field.DBName)) &&
+			!ArrayElementExists(stmt.Selects, nf.shadow.DBName) {
+			stmt.Selects = append(stmt.Selects, nf.shadow.DBName)
+		}
+	}
+}
+
+// set sets the shadow of struct or slice of structs rv
+func (nf normalizedField) set(db *gorm.DB, rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			nf.set(db, reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		if rv.Type() != nf.field.Schema.ModelType {
+			return
+		}
+
+		v, _ := nf.field.ValueOf(db.Statement.Context, rv)
+		if err := nf.shadow.Set(db.Statement.Context, rv, NormalizePersian(cast.ToString(v))); err != nil {
+			_ = db.AddError(err)
+		}
+	}
+}
+
+// normalizedFieldsOf returns the fields of sch tagged with `normalize` which have a shadow field
+func normalizedFieldsOf(sch *schema.Schema) []normalizedField {
+	if v, ok := normalizedFieldsCache.Load(sch); ok {
+		return v.([]normalizedField)
+	}
+
+	var fields []normalizedField
+	for _, f := range sch.Fields {
+		tag, ok := f.StructField.Tag.Lookup("normalize")
+		if !ok || f.DBName == "" {
+			continue
+		}
+
+		lang, shadowName, _ := strings.Cut(tag, ",")
+		if lang != "fa" {
+			continue
+		}
+
+		if shadow := sch.LookUpField(DefaultIfZero(shadowName, f.Name+"Normalized")); shadow != nil && shadow.DBName != "" {
+			fields = append(fields, normalizedField{field: f, shadow: shadow})
+		}
+	}
+
+	normalizedFieldsCache.Store(sch, fields)
+
+	return fields
+}
+
+// normalizedColumn returns the shadow column of col and the normalized value
+// if col is tagged with `normalize`, col and value are returned otherwise
+func normalizedColumn(columns map[string]string, col string, value string) (string, string, bool) {
+	prefix, name := "", col
+	if i := strings.LastIndex(col, "."); i >= 0 {
+		prefix, name = col[:i+1], col[i+1:]
+	}
+
+	if shadow, ok := columns[name]; ok {
+		return prefix + shadow, NormalizePersian(value), true
+	}
+
+	return col, value, false
+}
+
+// normalizedColumnsOf maps the columns tagged with `normalize` to their shadow columns,
+// nil is returned if db has no model
+func normalizedColumnsOf(db *gorm.DB) map[string]string {
+	if db.Statement.Model == nil {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(db.Statement.Model); err != nil {
+		return nil
+	}
+
+	columns := map[string]string{}
+	for _, nf := range normalizedFieldsOf(stmt.Schema) {
+		columns[nf.field.DBName] = nf.shadow.DBName
+	}
+
+	return columns
+}
@@ -0,0 +1,90 @@
+package simutils
+
+import (
+	"testing"
+)
+
+type normalizeTestItem struct {
+	Model
+	Name           string `normalize:"fa"`
+	NameNormalized string `gorm:"index"`
+	Title          string `normalize:"fa,TitleSearch"`
+	TitleSearch    string
+}
+
+func TestNormalizePersian(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "arabic letters", s: "علي كريمي", want: "علی کریمی"},
+		{name: "digits", s: "۱۲۳٤٥٦", want: "123456"},
+		{name: "zwnj", s: "می‌روم", want: "می روم"},
+		{name: "diacritics and tatweel", s: "كِتـــابٌ", want: "کتاب"},
+		{name: "spaces", s: "  a   b ", want: "a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizePersian(tt.s); got != tt.want {
+				t.Errorf("NormalizePersian() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePlugin(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:normalize_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+
+	if err := db.Use(NormalizePlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&normalizeTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	items := []normalizeTestItem{{Model: Model{ID: 1}, Name: "علي", Title: "كتاب"}, {Model: Model{ID: 2}, Name: "رضا"}}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if items[0].NameNormalized != "علی" || items[0].TitleSearch != "کتاب" {
+		t.Errorf("Create() shadows = %q, %q", items[0].NameNormalized, items[0].TitleSearch)
+	}
+
+	db.Model(&items[1]).Update("name", "مريم")
+	db.Model(&items[0]).Updates(map[string]interface{}{"Title": "دفتر٢"})
+	db.Model(&normalizeTestItem{Model: Model{ID: 1}}).Select("name").Updates(&normalizeTestItem{Name: "علي‌رضا"})
+
+	tests := []struct {
+		name    string
+		filters map[string][]FilterValue
+		want    PID
+	}{
+		{name: "update column", filters: map[string][]FilterValue{"name": {{Operator: "eq", Value: "مريم"}}}, want: 2},
+		{name: "updates map", filters: map[string][]FilterValue{"title": {{Operator: "eq", Value: "دفتر2"}}}, want: 1},
+		{name: "updates selected struct", filters: map[string][]FilterValue{"name": {{Operator: "like", Value: "علی رضا%"}}}, want: 1},
+		{name: "in", filters: map[string][]FilterValue{"name": {{Operator: "in", Value: "x,مریم"}}}, want: 2},
+		{name: "in arabic forms", filters: map[string][]FilterValue{"name": {{Operator: "in", Value: "كتاب,مريم,علي"}}}, want: 2},
+		{name: "nin arabic forms", filters: map[string][]FilterValue{"name": {{Operator: "nin", Value: "رضا,علي‌رضا"}}}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb, err := ParseFilters(db.Model(&normalizeTestItem{}), SQLite, tt.filters, map[string][]string{"name": {"name"}, "title": {"normalize_test_items.title"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []normalizeTestItem
+			if err := qb.Find(&got).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].ID != tt.want {
+				t.Errorf("ParseFilters() = %v, want id %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package simutils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrPasswordHash = errors.New("unsupported password hash")
+)
+
+const (
+	// PasswordArgon2id hashes passwords by argon2id
+	PasswordArgon2id = "argon2id"
+	// PasswordBcrypt hashes passwords by bcrypt
+	PasswordBcrypt = "bcrypt"
+)
+
+type (
+	// PasswordHasher hashes passwords and verifies them, Verify reports rehash
+	// when the hash is made by another algorithm or parameters
+	PasswordHasher interface {
+		Hash(password string) (string, error)
+		Verify(hash, password string) (ok, rehash bool, err error)
+	}
+
+	// Argon2idHasher hashes passwords in the PHC string format of argon2id, zero parameters are
+	// the second recommended option of RFC 9106: 3 passes, 64 MiB memory, 4 lanes and 32 bytes keys
+	Argon2idHasher struct {
+		Time    uint32
+		Memory  uint32
+		Threads uint8
+		KeyLen  uint32
+	}
+
+	// BcryptHasher hashes passwords by bcrypt, bcrypt.DefaultCost is used if Cost is zero
+	BcryptHasher struct {
+		Cost int
+	}
+)
+
+// NewPasswordHasher returns the hasher of algorithm, argon2id by default
+func NewPasswordHasher(algorithm string) (PasswordHasher, error) {
+	switch algorithm {
+	case "", PasswordArgon2id:
+		return &Argon2idHasher{}, nil
+	case PasswordBcrypt:
+		return &BcryptHasher{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrPasswordHash, algorithm)
+	}
+}
+
+func (h *Argon2idHasher) params() (time, memory uint32, threads uint8, keyLen uint32) {
+	return DefaultIfZero(h.Time, 3), DefaultIfZero(h.Memory, 64*1024), DefaultIfZero(h.Threads, 4), DefaultIfZero(h.KeyLen, 32)
+}
+
+// Hash returns the argon2id hash of password with a random salt
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	t, m, p, l := h.params()
+	key := argon2.IDKey([]byte(password), salt, t, m, p, l)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, m, t, p,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify verifies argon2id hashes by their own parameters and bcrypt hashes which are rehashed
+func (h *Argon2idHasher) Verify(hash, password string) (ok, rehash bool, err error) {
+	if isBcryptHash(hash) {
+		ok, _, err = (&BcryptHasher{}).Verify(hash, password)
+		return ok, ok, err
+	}
+
+	var (
+		version int
+		t, m    uint32
+		p       uint8
+	)
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != PasswordArgon2id {
+		return false, false, ErrPasswordHash
+	}
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrPasswordHash
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &m, &t, &p); err != nil {
+		return false, false, ErrPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, ErrPasswordHash
+	}
+
+	got := argon2.IDKey([]byte(password), salt, t, m, p, uint32(len(key)))
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return false, false, nil
+	}
+
+	wantT, wantM, wantP, wantL := h.params()
+
+	return true, t != wantT || m != wantM || p != wantP || uint32(len(key)) != wantL, nil
+}
+
+// Hash returns the bcrypt hash of password
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), DefaultIfZero(h.Cost, bcrypt.DefaultCost))
+	return string(b), err
+}
+
+// Verify verifies bcrypt hashes and argon2id hashes which are rehashed
+func (h *BcryptHasher) Verify(hash, password string) (ok, rehash bool, err error) {
+	if !isBcryptHash(hash) {
+		ok, _, err = (&Argon2idHasher{}).Verify(hash, password)
+		return ok, ok, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return false, false, ErrPasswordHash
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return true, err == nil && cost != DefaultIfZero(h.Cost, bcrypt.DefaultCost), nil
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,63 @@
+package simutils
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	argon := &Argon2idHasher{Memory: 8 * 1024}
+	bcryptHasher := &BcryptHasher{Cost: bcrypt.MinCost}
+
+	argonHash, err := argon.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcryptHasher.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		hasher     PasswordHasher
+		hash       string
+		password   string
+		wantOK     bool
+		wantRehash bool
+		wantErr    bool
+	}{
+		{name: "argon2id", hasher: argon, hash: argonHash, password: "secret", wantOK: true},
+		{name: "argon2id wrong password", hasher: argon, hash: argonHash, password: "wrong"},
+		{name: "argon2id other params", hasher: &Argon2idHasher{Memory: 16 * 1024}, hash: argonHash, password: "secret", wantOK: true, wantRehash: true},
+		{name: "bcrypt", hasher: bcryptHasher, hash: bcryptHash, password: "secret", wantOK: true},
+		{name: "bcrypt other cost", hasher: &BcryptHasher{}, hash: bcryptHash, password: "secret", wantOK: true, wantRehash: true},
+		{name: "bcrypt by argon2id", hasher: argon, hash: bcryptHash, password: "secret", wantOK: true, wantRehash: true},
+		{name: "argon2id by bcrypt", hasher: bcryptHasher, hash: argonHash, password: "secret", wantOK: true, wantRehash: true},
+		{name: "invalid hash", hasher: argon, hash: "plain", password: "plain", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := tt.hasher.Verify(tt.hash, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Errorf("Verify() = %v, %v, want %v, %v", ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestArgon2idHasher_Defaults(t *testing.T) {
+	hash, err := (&Argon2idHasher{}).Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Errorf("Hash() = %s, want the parameters of RFC 9106", hash)
+	}
+}
@@ -0,0 +1,134 @@
+package simutils
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEApplicationProblemJSON is the content type of RFC 7807 problem details
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// ErrorFormat is the format of error responses of HttpServer
+type ErrorFormat string
+
+const (
+	// ErrorFormatTemplate responds errors by ResponseTemplate unless problem+json is accepted
+	ErrorFormatTemplate ErrorFormat = "template"
+	// ErrorFormatProblem responds errors by ProblemDetails
+	ErrorFormatProblem ErrorFormat = "problem"
+)
+
+// errorFormatKey is the echo context key of the ErrorFormat of server
+const errorFormatKey = "simutils:error_format"
+
+// ProblemTypeBaseURI is the base of problem types, the type is the base joined with the error code.
+// The type is about:blank if it is empty.
+var ProblemTypeBaseURI = ""
+
+// ProblemDetails is an error response of RFC 7807
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the error code of ResponseTemplate
+	Code   ErrorCode    `json:"code,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// ErrorFormatMiddleware sets the error format of responses of Reply and HTTPErrorHandler
+func ErrorFormatMiddleware(format ErrorFormat) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set(errorFormatKey, format)
+			return next(ctx)
+		}
+	}
+}
+
+// NewProblemDetails converts an error template to problem details of instance
+func NewProblemDetails(template *ResponseTemplate, instance string) *ProblemDetails {
+	p := &ProblemDetails{
+		Type:     "about:blank",
+		Title:    template.Status,
+		Status:   template.Code,
+		Detail:   problemDetail(template.Message),
+		Instance: instance,
+		Code:     template.ErrorCode,
+	}
+
+	if p.Code == "" {
+		p.Code = statusErrorCode(p.Status)
+	}
+
+	if ProblemTypeBaseURI != "" {
+		p.Type = strings.TrimSuffix(ProblemTypeBaseURI, "/") + "/" + string(p.Code)
+	}
+
+	if fields, ok := template.Data.(ValidationErrors); ok {
+		p.Errors = fields
+	}
+
+	return p
+}
+
+// ReplyProblem responds template as problem details of the request
+func ReplyProblem(ctx echo.Context, template *ResponseTemplate) error {
+	b, err := json.Marshal(NewProblemDetails(template, ctx.Request().RequestURI))
+	if err != nil {
+		return err
+	}
+
+	return ctx.Blob(template.Code, MIMEApplicationProblemJSON, b)
+}
+
+// WantsProblem reports whether errors of ctx are responded as problem details,
+// by ErrorFormatProblem of server or Accept header of request
+func WantsProblem(ctx echo.Context) bool {
+	if format, ok := ctx.Get(errorFormatKey).(ErrorFormat); ok && format == ErrorFormatProblem {
+		return true
+	}
+
+	for _, accept := range strings.Split(ctx.Request().Header.Get(echo.HeaderAccept), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mediaType == MIMEApplicationProblemJSON {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replyTemplate responds template by Render, error templates are responded as problem details if they are wanted
+func replyTemplate(ctx echo.Context, status int, template *ResponseTemplate) error {
+	if status >= 400 && WantsProblem(ctx) {
+		t := *template
+		t.Code, t.Status = status, http.StatusText(status)
+		return ReplyProblem(ctx, &t)
+	}
+
+	return Render(ctx, status, template)
+}
+
+// problemDetail returns the text of a template message
+func problemDetail(message any) string {
+	switch m := message.(type) {
+	case nil:
+		return ""
+	case string:
+		return m
+	case []string:
+		return strings.Join(m, "; ")
+	case error:
+		if detail := errorMessages(m, DefaultLanguage); detail != nil {
+			return problemDetail(detail)
+		}
+	}
+
+	b, _ := json.Marshal(message)
+	return strings.Trim(string(b), `"`)
+}
@@ -0,0 +1,129 @@
+package simutils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestProblemDetails(t *testing.T) {
+	var h HttpServer
+	if err := json.Unmarshal([]byte(`{"error_format": "problem"}`), &h); err != nil {
+		t.Fatal(err)
+	}
+	h.Echo().POST("/orders", func(echo.Context) error {
+		return ValidationErrors{{Field: "email", Message: "is required", Rule: "required"}}
+	})
+	h.Echo().GET("/orders/:id", func(ctx echo.Context) error {
+		return Reply(ctx, http.StatusNotFound, ErrRecordNotFound, nil, nil)
+	})
+
+	var tmpl HttpServer
+	if err := json.Unmarshal([]byte(`{}`), &tmpl); err != nil {
+		t.Fatal(err)
+	}
+	tmpl.Echo().GET("/orders/:id", func(ctx echo.Context) error { return ErrNotFound })
+
+	tests := []struct {
+		name        string
+		e           *echo.Echo
+		method      string
+		target      string
+		accept      string
+		wantType    string
+		wantProblem *ProblemDetails
+	}{
+		{
+			name:     "validation",
+			e:        h.Echo(),
+			method:   http.MethodPost,
+			target:   "/orders",
+			wantType: MIMEApplicationProblemJSON,
+			wantProblem: &ProblemDetails{
+				Type:     "about:blank",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "validation failed",
+				Instance: "/orders",
+				Code:     ErrCodeValidation,
+				Errors:   []FieldError{{Field: "email", Message: "is required", Rule: "required"}},
+			},
+		},
+		{
+			name:     "reply",
+			e:        h.Echo(),
+			method:   http.MethodGet,
+			target:   "/orders/1",
+			wantType: MIMEApplicationProblemJSON,
+			wantProblem: &ProblemDetails{
+				Type:     "about:blank",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "record not found",
+				Instance: "/orders/1",
+				Code:     ErrCodeRecordNotFound,
+			},
+		},
+		{
+			name:     "success keeps template",
+			e:        h.Echo(),
+			method:   http.MethodGet,
+			target:   "/healthinfo",
+			wantType: echo.MIMEApplicationJSON,
+		},
+		{
+			name:     "template",
+			e:        tmpl.Echo(),
+			method:   http.MethodGet,
+			target:   "/orders/1",
+			wantType: echo.MIMEApplicationJSON,
+		},
+		{
+			name:     "negotiated",
+			e:        tmpl.Echo(),
+			method:   http.MethodGet,
+			target:   "/orders/1?expand=items",
+			accept:   "application/problem+json, application/json;q=0.9",
+			wantType: MIMEApplicationProblemJSON,
+			wantProblem: &ProblemDetails{
+				Type:     "about:blank",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "not found",
+				Instance: "/orders/1?expand=items",
+				Code:     ErrCodeNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			tt.e.ServeHTTP(rec, req)
+
+			if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, tt.wantType) {
+				t.Fatalf("content type = %s, want %s", ct, tt.wantType)
+			}
+			if tt.wantProblem == nil {
+				return
+			}
+
+			var got ProblemDetails
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(&got, tt.wantProblem) {
+				t.Errorf("problem = %+v, want %+v", got, tt.wantProblem)
+			}
+		})
+	}
+}
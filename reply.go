@@ -0,0 +1,85 @@
+package simutils
+
+import (
+	"net/http"
+
+	"github.com/alifakhimi/simple-utils-go/multierror"
+	"github.com/labstack/echo/v4"
+)
+
+// Response is a typed ResponseTemplate, data is serialized directly with the same json shape
+type Response[T any] struct {
+	Status    string    `json:"status"`
+	Code      int       `json:"code"`
+	Message   any       `json:"message"`
+	Data      T         `json:"data"`
+	Meta      any       `json:"meta,omitempty"`
+	Links     any       `json:"links,omitempty"`
+	ErrorCode ErrorCode `json:"error_code,omitempty"`
+}
+
+// NewResponse returns the response of any http status with the message of err,
+// the message of 201 is Created like ResponseCreated
+func NewResponse[T any](status int, data T, err error, meta any) *Response[T] {
+	resp := &Response[T]{
+		Status: http.StatusText(status),
+		Code:   status,
+		Data:   data,
+		Meta:   meta,
+	}
+
+	if err != nil {
+		resp.Message = multierror.Join(err)
+		if appErr, ok := appErrorOf(err); ok && status >= http.StatusBadRequest {
+			resp.ErrorCode = appErr.Code
+		}
+	} else if status == http.StatusCreated {
+		resp.Message = "Created"
+	}
+
+	return resp
+}
+
+// Template returns the untyped template of r
+func (r *Response[T]) Template() *ResponseTemplate {
+	return &ResponseTemplate{
+		Status:    r.Status,
+		Code:      r.Code,
+		Message:   r.Message,
+		Data:      r.Data,
+		Meta:      r.Meta,
+		Links:     r.Links,
+		ErrorCode: r.ErrorCode,
+	}
+}
+
+// Respond responds data with status in the negotiated format,
+// error statuses are responded as problem details if they are wanted
+func Respond[T any](ctx echo.Context, status int, data T, err error, meta any) error {
+	resp := NewResponse(status, data, err, meta)
+	if status >= http.StatusBadRequest && WantsProblem(ctx) {
+		return replyTemplate(ctx, status, resp.Template())
+	}
+
+	return Render(ctx, status, resp)
+}
+
+// OK responds data with 200
+func OK[T any](ctx echo.Context, data T, meta any) error {
+	return Respond(ctx, http.StatusOK, data, nil, meta)
+}
+
+// Created responds the created data with 201
+func Created[T any](ctx echo.Context, data T) error {
+	return Respond(ctx, http.StatusCreated, data, nil, nil)
+}
+
+// Page responds a page of items with 200 and its pagination as meta,
+// items are exported if csv or xlsx is negotiated, see RenderList
+func Page[T any](ctx echo.Context, items []T, paginate *PaginateTemplate) error {
+	if items == nil {
+		items = []T{}
+	}
+
+	return RenderList(ctx, http.StatusOK, NewResponse(http.StatusOK, items, nil, paginate).Template())
+}
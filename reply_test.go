@@ -0,0 +1,88 @@
+package simutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRespond(t *testing.T) {
+	type replyTestItem struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	item := replyTestItem{ID: 1, Name: "a"}
+
+	tests := []struct {
+		name       string
+		handler    echo.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "ok",
+			handler:    func(ctx echo.Context) error { return OK(ctx, item, nil) },
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"OK","code":200,"message":null,"data":{"id":1,"name":"a"}}`,
+		},
+		{
+			name: "ok as reply",
+			handler: func(ctx echo.Context) error {
+				return Reply(ctx, http.StatusOK, nil, map[string]any{"id": 1, "name": "a"}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"OK","code":200,"message":null,"data":{"id":1,"name":"a"}}`,
+		},
+		{
+			name:       "created",
+			handler:    func(ctx echo.Context) error { return Created(ctx, &item) },
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"status":"Created","code":201,"message":"Created","data":{"id":1,"name":"a"}}`,
+		},
+		{
+			name:       "page",
+			handler:    func(ctx echo.Context) error { return Page[replyTestItem](ctx, nil, CreatePaginateTemplate(0, 0, 10)) },
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"OK","code":200,"message":null,"data":[],"meta":{"pages":0,"total":0,"limit":10,"offset":0,"page":1,"count":0,"next":null,"prev":null}}`,
+		},
+		{
+			name: "any status",
+			handler: func(ctx echo.Context) error {
+				return Respond[any](ctx, http.StatusConflict, nil, ErrVersionConflict, nil)
+			},
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"status":"Conflict","code":409,"message":"record version conflict","data":null,"error_code":"version_conflict"}`,
+		},
+		{
+			name:       "reply any status",
+			handler:    func(ctx echo.Context) error { return Reply(ctx, http.StatusAccepted, nil, nil, nil) },
+			wantStatus: http.StatusAccepted,
+			wantBody:   `{"status":"Accepted","code":202,"message":null,"data":null}`,
+		},
+		{
+			name:       "reply template",
+			handler:    func(ctx echo.Context) error { return ReplyTemplate(ctx, http.StatusOK, nil, item, nil) },
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"OK","code":200,"message":null,"data":{"id":1,"name":"a"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/", tt.handler)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Body.String(); got != tt.wantBody+"\n" {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
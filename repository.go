@@ -0,0 +1,187 @@
+package simutils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrVersionConflict the record is changed by another request
+	ErrVersionConflict = DefineError(ErrCodeVersionConflict, http.StatusConflict, "record version conflict")
+)
+
+// SystemItem is implemented by models that have records which must not be deleted
+type SystemItem interface {
+	IsSystemItem() bool
+}
+
+// Repository provides CRUD operations on models embedding Model or CommonTableFields
+type Repository[T any] struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a repository of T
+func NewRepository[T any](db *gorm.DB) *Repository[T] {
+	return &Repository[T]{db: db}
+}
+
+// DB returns the transaction of ctx or the gorm db of repository, bound to ctx
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return DBFromContext(ctx, r.db)
+}
+
+// Get returns the record with id, scopes can select columns or preload associations
+func (r *Repository[T]) Get(ctx context.Context, id PID, scopes ...func(*gorm.DB) *gorm.DB) (*T, error) {
+	item := new(T)
+	if err := r.DB(ctx).Scopes(scopes...).First(item, id).Error; err != nil {
+		return nil, TranslateGormError(err)
+	}
+
+	return item, nil
+}
+
+// List returns a page of records using ParseURL query params
+func (r *Repository[T]) List(ctx echo.Context, spec ListSpec) (*ResponseTemplate, error) {
+	tpl, err := List[T](ctx, DBFromContext(ctx.Request().Context(), r.db), spec)
+	return tpl, TranslateGormError(err)
+}
+
+// Create inserts item
+func (r *Repository[T]) Create(ctx context.Context, item *T) error {
+	if ctf := commonTableFieldsOf(item); ctf != nil && ctf.Version == 0 {
+		ctf.Version = 1
+	}
+
+	return TranslateGormError(r.DB(ctx).Create(item).Error)
+}
+
+// Update saves all fields of item. If T embeds CommonTableFields the update
+// only succeeds when the stored version equals item version, then version is increased.
+func (r *Repository[T]) Update(ctx context.Context, item *T) error {
+	var (
+		tx  = r.DB(ctx).Model(item).Select("*").Omit("CreatedAt", "DeletedAt", clause.Associations)
+		ctf = commonTableFieldsOf(item)
+	)
+
+	if ctf != nil {
+		version := ctf.Version
+		tx = tx.Where("version = ?", version)
+		ctf.Version++
+
+		if res := tx.Updates(item); res.Error != nil || res.RowsAffected == 0 {
+			ctf.Version = version
+			if res.Error != nil {
+				return TranslateGormError(res.Error)
+			} else if _, err := r.Get(ctx, ctf.ID); err != nil {
+				return err
+			}
+			return ErrVersionConflict
+		}
+
+		return nil
+	}
+
+	if res := tx.Updates(item); res.Error != nil {
+		return TranslateGormError(res.Error)
+	} else if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Delete soft deletes the record with id if T has DeletedAt field,
+// system items can not be deleted
+func (r *Repository[T]) Delete(ctx context.Context, id PID) error {
+	item, err := r.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if si, ok := any(item).(SystemItem); ok && si.IsSystemItem() {
+		return ErrSystemItemDelete
+	}
+
+	if res := r.DB(ctx).Delete(item); res.Error != nil {
+		return TranslateGormError(res.Error)
+	} else if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Restore restores the soft deleted record with id
+func (r *Repository[T]) Restore(ctx context.Context, id PID) (*T, error) {
+	item := new(T)
+	if err := r.DB(ctx).Unscoped().First(item, id).Error; err != nil {
+		return nil, TranslateGormError(err)
+	}
+
+	if res := r.DB(ctx).Unscoped().Model(item).Update("deleted_at", nil); res.Error != nil {
+		return nil, TranslateGormError(res.Error)
+	}
+
+	return r.Get(ctx, id)
+}
+
+// TranslateGormError maps gorm and driver errors to package errors
+func TranslateGormError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrRecordNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey), isDuplicateKeyError(err):
+		return ErrAlreadyExist
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrForeignKeyViolated
+	}
+
+	return err
+}
+
+// isDuplicateKeyError checks driver messages of unique constraint violations
+// when gorm TranslateError is disabled
+func isDuplicateKeyError(err error) bool {
+	msg := strings.ToLower(err.Error())
+
+	for _, s := range []string{
+		"unique constraint failed",           // sqlite
+		"duplicate key value",                // postgres
+		"duplicate entry",                    // mysql
+		"cannot insert duplicate key",        // sqlserver
+		"violation of unique key constraint", // sqlserver
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commonTableFieldsOf returns the embedded CommonTableFields of v
+func commonTableFieldsOf(v any) *CommonTableFields {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if f := rv.FieldByName("CommonTableFields"); f.IsValid() && f.CanAddr() {
+		if ctf, ok := f.Addr().Interface().(*CommonTableFields); ok {
+			return ctf
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package simutils
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type repositoryTestItem struct {
+	CommonTableFields
+	Code   string `gorm:"unique"`
+	System bool
+}
+
+func (i repositoryTestItem) IsSystemItem() bool {
+	return i.System
+}
+
+func TestRepository(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:repository_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbConn.DB.AutoMigrate(&repositoryTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctx  = context.Background()
+		repo = NewRepository[repositoryTestItem](dbConn.DB)
+	)
+
+	item := &repositoryTestItem{CommonTableFields: CommonTableFields{ID: 1}, Code: "a"}
+	if err := repo.Create(ctx, item); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Create(ctx, &repositoryTestItem{CommonTableFields: CommonTableFields{ID: 2}, Code: "a"}); !errors.Is(err, ErrAlreadyExist) {
+		t.Errorf("Create() duplicate error = %v, want %v", err, ErrAlreadyExist)
+	}
+
+	stale := *item
+	item.Description = "first"
+	if err := repo.Update(ctx, item); err != nil || item.Version != 2 {
+		t.Fatalf("Update() error = %v, version = %d", err, item.Version)
+	}
+	stale.Description = "second"
+	if err := repo.Update(ctx, &stale); !errors.Is(err, ErrVersionConflict) || stale.Version != 1 {
+		t.Errorf("Update() stale error = %v, version = %d, want %v", err, stale.Version, ErrVersionConflict)
+	}
+
+	if err := repo.Delete(ctx, item.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.Get(ctx, item.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get() deleted error = %v, want %v", err, ErrRecordNotFound)
+	}
+	if restored, err := repo.Restore(ctx, item.ID); err != nil || restored.Description != "first" {
+		t.Errorf("Restore() = %v, error = %v", restored, err)
+	}
+
+	system := &repositoryTestItem{CommonTableFields: CommonTableFields{ID: 3}, Code: "system", System: true}
+	if err := repo.Create(ctx, system); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, system.ID); !errors.Is(err, ErrSystemItemDelete) {
+		t.Errorf("Delete() system item error = %v, want %v", err, ErrSystemItemDelete)
+	}
+	if err := repo.Delete(ctx, 100); ErrorToHttpStatusCode(err) != 404 {
+		t.Errorf("Delete() missing item status = %d, want 404", ErrorToHttpStatusCode(err))
+	}
+}
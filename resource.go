@@ -0,0 +1,410 @@
+package simutils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// ResourceAction is an operation of a registered resource
+type ResourceAction string
+
+const (
+	ResourceList   ResourceAction = "list"
+	ResourceGet    ResourceAction = "get"
+	ResourceCreate ResourceAction = "create"
+	ResourceUpdate ResourceAction = "update"
+	ResourcePatch  ResourceAction = "patch"
+	ResourceDelete ResourceAction = "delete"
+)
+
+var (
+	// ErrResourceForbidden can be returned by ResourceOptions.Authorize
+	ErrResourceForbidden = errors.New("access to resource is forbidden")
+)
+
+// resourceProtectedFields are managed by repository and never bound from request body
+var resourceProtectedFields = []string{"id", "created_at", "updated_at", "deleted_at"}
+
+// resourceOwnerFields are the owner fields of records which are protected by ResourceOptions.Owned, see IsOwner
+var resourceOwnerFields = []string{"user_id", "owner_id", "owner_type"}
+
+// ResourceOptions configures the handlers mounted by RegisterResource
+type ResourceOptions[T any] struct {
+	// Name is the swagger tag of resource, default is the table name of T
+	Name string
+	// Actions limits the mounted handlers, all actions are mounted if it is empty
+	Actions []ResourceAction
+	// List is used by `GET /`
+	List ListSpec
+	// Writable is the json field names which can be set by POST, PUT and PATCH,
+	// all fields except id and timestamps are writable if it is empty
+	Writable []string
+	// Protected is the json field names which are never bound from request body
+	Protected []string
+	// Owned protects the owner fields of records, user_id, owner_id and owner_type, from request body
+	// and sets the current user as the owner of created records before Authorize, see IsOwner.
+	// Resources whose user_id or owner_id are ordinary references chosen by clients are not owned.
+	Owned bool
+	// Authorize is called before every action, item is nil on list.
+	// Returning an error responds 403 unless the error is mapped by ErrorStatus.
+	Authorize func(ctx echo.Context, action ResourceAction, item *T) error
+	// Validate is called before create and update.
+	// Returning an error responds 422 unless the error is mapped by ErrorStatus.
+	Validate func(ctx echo.Context, action ResourceAction, item *T) error
+	// Middlewares are applied to all mounted routes
+	Middlewares []echo.MiddlewareFunc
+}
+
+// resource holds the state of handlers of T
+type resource[T any] struct {
+	opts     ResourceOptions[T]
+	repo     *Repository[T]
+	fieldIdx map[string][]int
+}
+
+// RegisterResource mounts CRUD handlers of the gorm model T on group:
+// GET /, GET /:id, POST /, PUT /:id, PATCH /:id and DELETE /:id.
+// Routes are documented in the swagger served by HttpServer.
+func RegisterResource[T any](group *echo.Group, db *gorm.DB, opts ResourceOptions[T]) []*echo.Route {
+	var (
+		routes []*echo.Route
+		model  = reflect.TypeOf(new(T)).Elem()
+		r      = &resource[T]{
+			opts:     opts,
+			repo:     NewRepository[T](db),
+			fieldIdx: jsonFieldIndex(model),
+		}
+	)
+
+	// associations are never bound from request body
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err == nil {
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			name, _, _ := strings.Cut(rel.Field.StructField.Tag.Get("json"), ",")
+			delete(r.fieldIdx, DefaultIfZero(name, rel.Field.Name))
+		}
+	}
+
+	if opts.Owned {
+		r.opts.Protected = append(slices.Clip(opts.Protected), resourceOwnerFields...)
+	}
+
+	if r.opts.Name == "" {
+		r.opts.Name = GetTableName(new(T))
+	}
+
+	handlers := []struct {
+		action  ResourceAction
+		method  string
+		path    string
+		handler echo.HandlerFunc
+	}{
+		{ResourceList, http.MethodGet, "", r.list},
+		{ResourceGet, http.MethodGet, "/:id", r.get},
+		{ResourceCreate, http.MethodPost, "", r.create},
+		{ResourceUpdate, http.MethodPut, "/:id", r.update},
+		{ResourcePatch, http.MethodPatch, "/:id", r.update},
+		{ResourceDelete, http.MethodDelete, "/:id", r.delete},
+	}
+
+	ref := AddSwaggerDefinition(model)
+
+	for _, h := range handlers {
+		if len(opts.Actions) > 0 && !ItemExists(opts.Actions, h.action) {
+			continue
+		}
+
+		route := group.Add(h.method, h.path, h.handler, opts.Middlewares...)
+		routes = append(routes, route)
+
+		AddSwaggerOperation(h.method, route.Path, resourceOperation(r.opts.Name, h.action, ref))
+	}
+
+	return routes
+}
+
+func (r *resource[T]) list(ctx echo.Context) error {
+	if err := r.authorize(ctx, ResourceList, nil); err != nil {
+		return r.reply(ctx, http.StatusForbidden, err)
+	}
+
+	listSpec := r.opts.List
+	if user, ok := ctx.Get(CTXOwnerScope).(PID); ok {
+		if scope := OwnerScope(user, new(T)); scope != nil {
+			listSpec.Scopes = append(slices.Clip(listSpec.Scopes), scope)
+		}
+	}
+
+	tpl, err := r.repo.List(ctx, listSpec)
+	if err != nil {
+		return r.reply(ctx, http.StatusBadRequest, err)
+	}
+
+	return RenderList(ctx, tpl.Code, tpl)
+}
+
+func (r *resource[T]) get(ctx echo.Context) error {
+	var (
+		fields *FieldSet
+		scopes []func(*gorm.DB) *gorm.DB
+		err    error
+	)
+
+	// associations and columns of fields are limited by List like the fields of list
+	if fields, err = parseListFields[T](ctx, r.repo.db, r.opts.List, r.opts.List.Includes); err != nil {
+		return r.reply(ctx, http.StatusBadRequest, err)
+	} else if fields != nil {
+		scopes = append(scopes, fields.Apply)
+	}
+
+	item, err := r.find(ctx, scopes...)
+	if err != nil {
+		return r.reply(ctx, http.StatusBadRequest, err)
+	} else if err := r.authorize(ctx, ResourceGet, item); err != nil {
+		return r.reply(ctx, http.StatusForbidden, err)
+	}
+
+	if fields != nil {
+		data, err := fields.Filter(item)
+		if err != nil {
+			return r.reply(ctx, http.StatusInternalServerError, err)
+		}
+		return OK(ctx, data, nil)
+	}
+
+	return OK(ctx, item, nil)
+}
+
+func (r *resource[T]) create(ctx echo.Context) error {
+	item := new(T)
+
+	if err := r.bind(ctx, item, ResourceCreate); err != nil {
+		return r.reply(ctx, http.StatusBadRequest, err)
+	}
+
+	if user, ok := ContextOf(ctx).CurrentUser(); ok && r.opts.Owned {
+		setOwner(user.ID, item)
+	}
+
+	if err := r.validate(ctx, ResourceCreate, item); err != nil {
+		return r.reply(ctx, http.StatusUnprocessableEntity, err)
+	} else if err := r.authorize(ctx, ResourceCreate, item); err != nil {
+		return r.reply(ctx, http.StatusForbidden, err)
+	} else if err := r.repo.Create(ctx.Request().Context(), item); err != nil {
+		return r.reply(ctx, http.StatusInternalServerError, err)
+	}
+
+	return Created(ctx, item)
+}
+
+func (r *resource[T]) update(ctx echo.Context) error {
+	action := ResourceUpdate
+	if ctx.Request().Method == http.MethodPatch {
+		action = ResourcePatch
+	}
+
+	if item, err := r.find(ctx); err != nil {
+		return r.reply(ctx, http.StatusBadRequest, err)
+	} else if err := r.authorize(ctx, action, item); err != nil {
+		return r.reply(ctx, http.StatusForbidden, err)
+	} else if err := r.bind(ctx, item, action); err != nil {
+		return r.reply(ctx, http.StatusBadRequest, err)
+	} else if err := r.validate(ctx, action, item); err != nil {
+		return r.reply(ctx, http.StatusUnprocessableEntity, err)
+	} else if err := r.repo.Update(ctx.Request().Context(), item); err != nil {
+		return r.reply(ctx, http.StatusInternalServerError, err)
+	} else {
+		return OK(ctx, item, nil)
+	}
+}
+
+func (r *resource[T]) delete(ctx echo.Context) error {
+	if item, err := r.find(ctx); err != nil {
+		return r.reply(ctx, http.StatusBadRequest, err)
+	} else if err := r.authorize(ctx, ResourceDelete, item); err != nil {
+		return r.reply(ctx, http.StatusForbidden, err)
+	} else if err := r.repo.Delete(ctx.Request().Context(), GetID(item)); err != nil {
+		return r.reply(ctx, http.StatusInternalServerError, err)
+	} else {
+		return OK[any](ctx, nil, nil)
+	}
+}
+
+// find loads the record of `:id` path param
+func (r *resource[T]) find(ctx echo.Context, scopes ...func(*gorm.DB) *gorm.DB) (*T, error) {
+	id, err := ParsePID(ctx.Param("id"))
+	if err != nil {
+		return nil, ErrInvalidRequest
+	}
+
+	return r.repo.Get(ctx.Request().Context(), id, scopes...)
+}
+
+// bind applies the writable fields of request body on item,
+// PUT resets the writable fields which are missing in body
+func (r *resource[T]) bind(ctx echo.Context, item *T, action ResourceAction) error {
+	body := map[string]any{}
+	if err := (&echo.DefaultBinder{}).BindBody(ctx, &body); err != nil {
+		return ErrInvalidRequest
+	}
+
+	for k := range body {
+		if !r.writable(k) {
+			delete(body, k)
+		}
+	}
+
+	if action == ResourceUpdate {
+		rv := reflect.ValueOf(item).Elem()
+		for k, idx := range r.fieldIdx {
+			if _, exists := body[k]; !exists && k != "version" && r.writable(k) {
+				rv.FieldByIndex(idx).SetZero()
+			}
+		}
+	}
+
+	if err := JSONTo(body, item); err != nil {
+		return ErrInvalidRequest
+	}
+
+	if c, ok := ctx.(*Context); ok {
+		c.RequestModel = item
+	}
+
+	return nil
+}
+
+// writable checks the json field is allowed to be bound
+func (r *resource[T]) writable(field string) bool {
+	if ArrayElementExists(resourceProtectedFields, field) || ArrayElementExists(r.opts.Protected, field) {
+		return false
+	} else if _, exists := r.fieldIdx[field]; !exists {
+		return false
+	}
+
+	return len(r.opts.Writable) == 0 || field == "version" || ArrayElementExists(r.opts.Writable, field)
+}
+
+// authorize runs Authorize hook
+func (r *resource[T]) authorize(ctx echo.Context, action ResourceAction, item *T) error {
+	if r.opts.Authorize == nil {
+		return nil
+	}
+
+	return r.opts.Authorize(ctx, action, item)
+}
+
+// validate runs Validate hook
+func (r *resource[T]) validate(ctx echo.Context, action ResourceAction, item *T) error {
+	if r.opts.Validate == nil {
+		return nil
+	}
+
+	return r.opts.Validate(ctx, action, item)
+}
+
+// reply responds err using its mapped status or status
+func (r *resource[T]) reply(ctx echo.Context, status int, err error) error {
+	if code, errCode := ErrorStatus(err); errCode != ErrCodeInternal {
+		status = code
+	}
+
+	return replyTemplate(ctx, status, GetWithCode(nil, status, err))
+}
+
+// jsonFieldIndex maps json field names of t to their field index,
+// fields of embedded structs are included
+func jsonFieldIndex(t reflect.Type) map[string][]int {
+	fields := map[string][]int{}
+
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			idx := append(append([]int{}, index...), i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type, idx)
+				continue
+			}
+
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			if _, exists := fields[name]; !exists {
+				fields[name] = idx
+			}
+		}
+	}
+	walk(t, nil)
+
+	return fields
+}
+
+// resourceOperation documents an action of resource
+func resourceOperation(name string, action ResourceAction, ref *spec.Schema) *spec.Operation {
+	var (
+		op       = spec.NewOperation(fmt.Sprintf("%s_%s", name, action)).WithTags(name)
+		response = func(data *spec.Schema) *spec.Response {
+			schema := &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"object"}, Properties: map[string]spec.Schema{
+				"status":  *spec.StringProperty(),
+				"code":    *spec.Int32Property(),
+				"message": {},
+				"data":    *data,
+				"meta":    {},
+			}}}
+			return spec.NewResponse().WithDescription(http.StatusText(http.StatusOK)).WithSchema(schema)
+		}
+		errResponse = spec.NewResponse().WithDescription("error").WithSchema(response(&spec.Schema{}).Schema)
+	)
+
+	op.Produces = []string{echo.MIMEApplicationJSON, echo.MIMEApplicationXML, MIMEApplicationMsgPack}
+
+	switch action {
+	case ResourceList:
+		op.WithSummary("List " + name)
+		op.Produces = append(op.Produces, MIMETextCSV, MIMEApplicationXLSX)
+		for _, q := range []string{"limit", "offset", "sort", "includes", "fields"} {
+			op.AddParam(spec.QueryParam(q).Typed("string", ""))
+		}
+		op.RespondsWith(http.StatusOK, response(spec.ArrayProperty(ref)))
+	case ResourceGet:
+		op.WithSummary("Get " + name)
+		op.AddParam(spec.QueryParam("fields").Typed("string", ""))
+		op.RespondsWith(http.StatusOK, response(ref))
+	case ResourceCreate:
+		op.WithSummary("Create " + name)
+		op.Consumes = []string{echo.MIMEApplicationJSON}
+		op.AddParam(spec.BodyParam("body", ref).AsRequired())
+		op.RespondsWith(http.StatusCreated, response(ref))
+	case ResourceUpdate, ResourcePatch:
+		op.WithSummary(strings.ToUpper(string(action[:1])) + string(action[1:]) + " " + name)
+		op.Consumes = []string{echo.MIMEApplicationJSON}
+		op.AddParam(spec.BodyParam("body", ref).AsRequired())
+		op.RespondsWith(http.StatusOK, response(ref))
+	case ResourceDelete:
+		op.WithSummary("Delete " + name)
+		op.RespondsWith(http.StatusOK, response(&spec.Schema{}))
+	}
+
+	if action != ResourceList && action != ResourceCreate {
+		op.AddParam(spec.PathParam("id").Typed("integer", "int64"))
+	}
+
+	op.WithDefaultResponse(errResponse)
+
+	return op
+}
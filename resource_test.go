@@ -0,0 +1,295 @@
+package simutils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/swaggo/swag"
+)
+
+type resourceTestItem struct {
+	CommonTableFields
+	Name  string `json:"name"`
+	Price int    `json:"price"`
+	Code  string `json:"code"`
+}
+
+func TestRegisterResource(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:resource_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbConn.DB.AutoMigrate(&resourceTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	RegisterResource(e.Group("/api/items"), dbConn.DB, ResourceOptions[resourceTestItem]{
+		Writable: []string{"name", "price"},
+		List:     ListSpec{Columns: map[string][]string{"price": {"price"}}},
+		Authorize: func(ctx echo.Context, action ResourceAction, item *resourceTestItem) error {
+			if action == ResourceDelete && item.Price > 100 {
+				return ErrResourceForbidden
+			}
+			return nil
+		},
+		Validate: func(ctx echo.Context, action ResourceAction, item *resourceTestItem) error {
+			if item.Name == "" {
+				return errors.New("name is required")
+			}
+			return nil
+		},
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		accept     string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "create", method: http.MethodPost, path: "/api/items", body: `{"id":5,"name":"pen","price":10,"code":"x"}`, wantStatus: http.StatusCreated, wantBody: `"code":""`},
+		{name: "create invalid", method: http.MethodPost, path: "/api/items", body: `{"price":10}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "get", method: http.MethodGet, path: "/api/items/1", wantStatus: http.StatusOK, wantBody: `"name":"pen"`},
+		{name: "get xml", method: http.MethodGet, path: "/api/items/1", accept: echo.MIMEApplicationXML, wantStatus: http.StatusOK, wantBody: `<name>pen</name>`},
+		{name: "get missing", method: http.MethodGet, path: "/api/items/9", wantStatus: http.StatusNotFound},
+		{name: "patch", method: http.MethodPatch, path: "/api/items/1", body: `{"price":200,"version":1}`, wantStatus: http.StatusOK, wantBody: `"name":"pen","price":200`},
+		{name: "patch stale version", method: http.MethodPatch, path: "/api/items/1", body: `{"price":300,"version":1}`, wantStatus: http.StatusConflict},
+		{name: "put resets missing fields", method: http.MethodPut, path: "/api/items/1", body: `{"name":"book","version":2}`, wantStatus: http.StatusOK, wantBody: `"name":"book","price":0`},
+		{name: "list", method: http.MethodGet, path: "/api/items?price=eq:0", wantStatus: http.StatusOK, wantBody: `"total":1`},
+		{name: "delete", method: http.MethodDelete, path: "/api/items/1", wantStatus: http.StatusOK},
+		{name: "delete missing", method: http.MethodDelete, path: "/api/items/1", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAccept, tt.accept)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%s %s status = %d, want %d, body = %s", tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s body = %s, want %s", tt.method, tt.path, rec.Body, tt.wantBody)
+			}
+		})
+	}
+
+	doc, err := swag.ReadDoc(SwaggerInstanceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var swagger struct {
+		Paths       map[string]map[string]any `json:"paths"`
+		Definitions map[string]any            `json:"definitions"`
+	}
+	if err := json.Unmarshal([]byte(doc), &swagger); err != nil {
+		t.Fatal(err)
+	}
+	if len(swagger.Paths["/api/items/{id}"]) != 4 || len(swagger.Paths["/api/items"]) != 2 {
+		t.Errorf("swagger paths = %v", swagger.Paths)
+	}
+	if _, ok := swagger.Definitions["resourceTestItem"]; !ok {
+		t.Errorf("swagger definitions = %v", swagger.Definitions)
+	}
+}
+
+type resourceTestCategory struct {
+	ID   PID    `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+}
+
+type resourceTestOwnedItem struct {
+	CommonTableFields
+	PolymorphicFields
+	Name       string                `json:"name"`
+	CategoryID PID                   `json:"category_id"`
+	Category   *resourceTestCategory `json:"category,omitempty"`
+}
+
+func TestRegisterResource_ProtectedFields(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:resource_protected_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	if err := db.AutoMigrate(&resourceTestCategory{}, &resourceTestOwnedItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&resourceTestOwnedItem{CommonTableFields: CommonTableFields{UserID: 1}, Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	RegisterResource(e.Group("/api/owned"), db, ResourceOptions[resourceTestOwnedItem]{Owned: true})
+	RegisterResource(e.Group("/api/referenced"), db, ResourceOptions[resourceTestOwnedItem]{Protected: []string{"category_id"}})
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		id        PID
+		wantUser  PID
+		wantOwner PID
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			path:   "/api/owned",
+			body:   `{"name":"b","user_id":2,"owner_id":2,"owner_type":"users","user":{"id":2},"category":{"id":3,"name":"x"}}`,
+			id:     2,
+		},
+		{
+			name:     "update",
+			method:   http.MethodPatch,
+			path:     "/api/owned/1",
+			body:     `{"name":"c","user_id":2,"owner_id":2,"owner_type":"users","version":0}`,
+			id:       1,
+			wantUser: 1,
+		},
+		{
+			name:      "create not owned",
+			method:    http.MethodPost,
+			path:      "/api/referenced",
+			body:      `{"name":"d","user_id":2,"owner_id":2,"owner_type":"users","category_id":3}`,
+			id:        3,
+			wantUser:  2,
+			wantOwner: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code >= http.StatusBadRequest {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+			}
+
+			var got resourceTestOwnedItem
+			if err := db.First(&got, tt.id).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got.UserID != tt.wantUser || got.OwnerID != tt.wantOwner || got.CategoryID != 0 {
+				t.Errorf("fields are bound: %+v", got)
+			}
+		})
+	}
+
+	var categories int64
+	if err := db.Model(&resourceTestCategory{}).Count(&categories).Error; err != nil {
+		t.Fatal(err)
+	}
+	if categories != 0 {
+		t.Errorf("association is created from request body")
+	}
+}
+
+func TestRegisterResource_UserRole(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:resource_user_role_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	if err := db.AutoMigrate(&User{}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	RegisterResource(e.Group("/api/users"), db, ResourceOptions[User]{Protected: UserProtectedFields})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"username":"u","role":"admin","status":2}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code >= http.StatusBadRequest {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	var got User
+	if err := db.First(&got, "username = ?", "u").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Role != "" || got.Status != PENDING {
+		t.Errorf("role or status is bound: %+v", got)
+	}
+
+	// role of the other models is an ordinary field
+	if err := db.AutoMigrate(&resourceTestMember{}); err != nil {
+		t.Fatal(err)
+	}
+	RegisterResource(e.Group("/api/members"), db, ResourceOptions[resourceTestMember]{})
+
+	req = httptest.NewRequest(http.MethodPost, "/api/members", strings.NewReader(`{"role":"editor"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var member resourceTestMember
+	if err := db.First(&member).Error; err != nil {
+		t.Fatal(err)
+	}
+	if member.Role != "editor" {
+		t.Errorf("role of member = %q, want editor", member.Role)
+	}
+}
+
+type resourceTestMember struct {
+	ID   PID    `json:"id" gorm:"primaryKey"`
+	Role string `json:"role"`
+}
+
+func TestRegisterResource_GetFields(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:resource_fields_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	if err := db.AutoMigrate(&resourceTestCategory{}, &resourceTestOwnedItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&resourceTestOwnedItem{Name: "a", Category: &resourceTestCategory{Name: "pens"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	RegisterResource(e.Group("/api/items"), db, ResourceOptions[resourceTestOwnedItem]{})
+	RegisterResource(e.Group("/api/included"), db, ResourceOptions[resourceTestOwnedItem]{List: ListSpec{Includes: []string{"Category"}}})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "root fields", path: "/api/items/1?fields=id,name", wantStatus: http.StatusOK, wantBody: `"name":"a"`},
+		{name: "association is not included", path: "/api/items/1?fields=name,category.name", wantStatus: http.StatusBadRequest},
+		{name: "association is included", path: "/api/included/1?fields=name,category.name", wantStatus: http.StatusOK, wantBody: `"category":{"name":"pens"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GET %s status = %d, want %d, body = %s", tt.path, rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("GET %s body = %s, want %s", tt.path, rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -14,6 +14,8 @@ type ResponseTemplate struct {
 	Data    interface{} `json:"data"`
 	Meta    interface{} `json:"meta,omitempty"`
 	Links   interface{} `json:"links,omitempty"`
+	// ErrorCode is a stable machine-readable code of error responses
+	ErrorCode ErrorCode `json:"error_code,omitempty"`
 }
 
 func (m ResponseTemplate) MarshalBinary() ([]byte, error) {
@@ -209,6 +211,16 @@ func ResponseMethodNotAllowed(data, msg interface{}) *ResponseTemplate {
 	}
 }
 
+// TooManyRequests ...
+func ResponseTooManyRequests(data, msg interface{}) *ResponseTemplate {
+	return &ResponseTemplate{
+		Code:    http.StatusTooManyRequests,
+		Status:  http.StatusText(http.StatusTooManyRequests),
+		Message: msg,
+		Data:    data,
+	}
+}
+
 // Ok ...
 func ResponseOk(data, msg interface{}, meta interface{}) *ResponseTemplate {
 	return &ResponseTemplate{
@@ -0,0 +1,310 @@
+package simutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNoSearchField model has no field tagged with `search`
+	ErrNoSearchField = errors.New("model has no search field")
+)
+
+// searchRowID is the primary key column which links sqlite FTS5 rows to the table rows
+const searchRowID = "id"
+
+// postgresSearchWeights maps search weights to postgres tsvector weights
+var postgresSearchWeights = map[int]string{1: "D", 2: "C", 3: "B", 4: "A"}
+
+// SearchField is a column used by full text search.
+// Fields are tagged with their weight from 1 (lowest) to 4 (highest), like `search:"4"`.
+type SearchField struct {
+	Column string
+	Weight int
+}
+
+// search is the compiled full text search of a query
+type search struct {
+	where clause.Expr
+	order clause.Expr
+}
+
+// SearchFields returns the fields of model tagged with `search`
+func SearchFields(db *gorm.DB, model any) ([]SearchField, string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, "", err
+	}
+
+	var fields []SearchField
+	for _, f := range stmt.Schema.Fields {
+		tag, ok := f.StructField.Tag.Lookup("search")
+		if !ok || f.DBName == "" {
+			continue
+		}
+
+		weight := cast.ToInt(tag)
+		if weight < 1 {
+			weight = 1
+		} else if weight > 4 {
+			weight = 4
+		}
+
+		fields = append(fields, SearchField{Column: f.DBName, Weight: weight})
+	}
+
+	if len(fields) == 0 {
+		return nil, "", ErrNoSearchField
+	}
+
+	return fields, stmt.Schema.Table, nil
+}
+
+// Search filters db by the full text query q on the search fields of model
+// and orders the result by relevance. Persian and Arabic spellings of terms are matched together.
+// The search index must exist, see MigrateSearchIndex.
+func Search(db *gorm.DB, model any, q string) *gorm.DB {
+	s, err := buildSearch(db, model, q)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	} else if s == nil {
+		return db
+	}
+
+	return orderBySearch(db.Where(s.where), s.order)
+}
+
+// orderBySearch appends the relevance order to the order by columns of db
+func orderBySearch(db *gorm.DB, order clause.Expr) *gorm.DB {
+	exprs := []clause.Expression{}
+	if c, ok := db.Statement.Clauses["ORDER BY"]; ok {
+		if orderBy, ok := c.Expression.(clause.OrderBy); ok {
+			for _, col := range orderBy.Columns {
+				if col.Desc {
+					exprs = append(exprs, clause.Expr{SQL: "? DESC", Vars: []any{col.Column}})
+				} else {
+					exprs = append(exprs, clause.Expr{SQL: "?", Vars: []any{col.Column}})
+				}
+			}
+		}
+	}
+
+	return db.Clauses(clause.OrderBy{Expression: clause.CommaExpression{Exprs: append(exprs, order)}})
+}
+
+// buildSearch compiles q for the driver of db, nil is returned when q has no term
+func buildSearch(db *gorm.DB, model any, q string) (*search, error) {
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	fields, table, err := SearchFields(db, model)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		quote   = db.Statement.Quote
+		columns = make([]string, len(fields))
+	)
+
+	for i, f := range fields {
+		columns[i] = quote(clause.Column{Table: table, Name: f.Column})
+	}
+
+	switch DriverOf(db) {
+	case PostgresSQL:
+		var (
+			docs  = make([]string, len(fields))
+			query = joinSearchTerms(terms, " & ", " | ", func(v string) string { return v + ":*" })
+		)
+
+		for i, f := range fields {
+			docs[i] = fmt.Sprintf("setweight(to_tsvector('simple', coalesce(%s, '')), '%s')", columns[i], postgresSearchWeights[f.Weight])
+		}
+		doc := strings.Join(docs, " || ")
+
+		return &search{
+			where: gorm.Expr(fmt.Sprintf("(%s) @@ to_tsquery('simple', ?)", doc), query),
+			order: gorm.Expr(fmt.Sprintf("ts_rank(%s, to_tsquery('simple', ?)) DESC", doc), query),
+		}, nil
+	case SQLServer:
+		var (
+			ranks []string
+			vars  []any
+			query = joinSearchTerms(terms, " AND ", " OR ", func(v string) string { return `"` + v + `*"` })
+		)
+
+		for i, f := range fields {
+			ranks = append(ranks, fmt.Sprintf("CASE WHEN CONTAINS(%s, ?) THEN %d ELSE 0 END", columns[i], f.Weight))
+			vars = append(vars, query)
+		}
+
+		return &search{
+			where: gorm.Expr(fmt.Sprintf("CONTAINS((%s), ?)", strings.Join(columns, ", ")), query),
+			order: gorm.Expr(fmt.Sprintf("(%s) DESC", strings.Join(ranks, " + ")), vars...),
+		}, nil
+	case MySQL:
+		var (
+			ranks []string
+			vars  []any
+			// every term is required
+			query = "+" + joinSearchTerms(terms, " +", " ", func(v string) string { return v + "*" })
+		)
+
+		for i, f := range fields {
+			ranks = append(ranks, fmt.Sprintf("%d * MATCH(%s) AGAINST(? IN BOOLEAN MODE)", f.Weight, columns[i]))
+			vars = append(vars, query)
+		}
+
+		return &search{
+			where: gorm.Expr(fmt.Sprintf("MATCH(%s) AGAINST(? IN BOOLEAN MODE)", strings.Join(columns, ", ")), query),
+			order: gorm.Expr(fmt.Sprintf("(%s) DESC", strings.Join(ranks, " + ")), vars...),
+		}, nil
+	case SQLite:
+		var (
+			ftsTable = quote(searchTableName(table))
+			pk       = quote(clause.Column{Table: table, Name: searchRowID})
+			weights  = make([]string, len(fields))
+			query    = joinSearchTerms(terms, " AND ", " OR ", func(v string) string { return `"` + v + `"*` })
+		)
+
+		for i, f := range fields {
+			weights[i] = cast.ToString(f.Weight)
+		}
+
+		return &search{
+			where: gorm.Expr(fmt.Sprintf("%s IN (SELECT rowid FROM %s WHERE %s MATCH ?)", pk, ftsTable, ftsTable), query),
+			order: gorm.Expr(fmt.Sprintf("(SELECT bm25(%s, %s) FROM %s WHERE %s MATCH ? AND rowid = %s)",
+				ftsTable, strings.Join(weights, ", "), ftsTable, ftsTable, pk), query),
+		}, nil
+	}
+
+	return nil, ErrInvalidDatabaseDriver
+}
+
+// MigrateSearchIndex creates the full text index of the search fields of model:
+// a GIN index on postgres, a FULLTEXT index on mysql and sqlserver and
+// an external content FTS5 table kept in sync by triggers on sqlite.
+// SQLite needs the `sqlite_fts5` build tag of go-sqlite3.
+func MigrateSearchIndex(db *gorm.DB, model any) error {
+	fields, table, err := SearchFields(db, model)
+	if err != nil {
+		return err
+	}
+
+	var (
+		quote   = db.Statement.Quote
+		index   = quote("idx_" + searchTableName(table))
+		columns = make([]string, len(fields))
+	)
+
+	for i, f := range fields {
+		columns[i] = quote(f.Column)
+	}
+
+	switch DriverOf(db) {
+	case PostgresSQL:
+		docs := make([]string, len(fields))
+		for i, f := range fields {
+			docs[i] = fmt.Sprintf("setweight(to_tsvector('simple', coalesce(%s, '')), '%s')", columns[i], postgresSearchWeights[f.Weight])
+		}
+
+		return db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN ((%s))", index, quote(table), strings.Join(docs, " || "))).Error
+	case MySQL:
+		if db.Migrator().HasIndex(table, "idx_"+searchTableName(table)) {
+			return nil
+		}
+
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD FULLTEXT %s (%s)", quote(table), index, strings.Join(columns, ", "))).Error; err != nil {
+			return err
+		}
+
+		// relevance is weighted per column
+		for i, f := range fields {
+			if len(fields) > 1 {
+				if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD FULLTEXT %s (%s)", quote(table), quote("idx_"+searchTableName(table)+"_"+f.Column), columns[i])).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	case SQLServer:
+		return db.Exec(fmt.Sprintf(`IF NOT EXISTS (SELECT 1 FROM sys.fulltext_catalogs WHERE is_default = 1)
+	CREATE FULLTEXT CATALOG simutils_search AS DEFAULT;
+IF NOT EXISTS (SELECT 1 FROM sys.fulltext_indexes WHERE object_id = OBJECT_ID(?))
+BEGIN
+	DECLARE @pk sysname = (SELECT name FROM sys.indexes WHERE object_id = OBJECT_ID(?) AND is_primary_key = 1);
+	EXEC('CREATE FULLTEXT INDEX ON %s (%s) KEY INDEX ' + @pk);
+END`, quote(table), strings.Join(columns, ", ")), table, table).Error
+	case SQLite:
+		var (
+			fts     = quote(searchTableName(table))
+			tbl     = quote(table)
+			newVals = "new." + strings.Join(columns, ", new.")
+			oldVals = "old." + strings.Join(columns, ", old.")
+			cols    = strings.Join(columns, ", ")
+		)
+
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, sql := range []string{
+				fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, content=%s, content_rowid='%s', tokenize='unicode61 remove_diacritics 2')", fts, cols, tbl, searchRowID),
+				fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER INSERT ON %s BEGIN INSERT INTO %s(rowid, %s) VALUES (new.%s, %s); END", quote(searchTableName(table)+"_ai"), tbl, fts, cols, searchRowID, newVals),
+				fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER DELETE ON %s BEGIN INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.%s, %s); END", quote(searchTableName(table)+"_ad"), tbl, fts, fts, cols, searchRowID, oldVals),
+				fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER UPDATE ON %s BEGIN INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.%s, %s); INSERT INTO %s(rowid, %s) VALUES (new.%s, %s); END",
+					quote(searchTableName(table)+"_au"), tbl, fts, fts, cols, searchRowID, oldVals, fts, cols, searchRowID, newVals),
+				fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", fts, fts),
+			} {
+				if err := tx.Exec(sql).Error; err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	return ErrInvalidDatabaseDriver
+}
+
+// searchTableName is the name of full text table or index of table
+func searchTableName(table string) string {
+	return table + "_search"
+}
+
+// searchTerms splits q into terms of letters and digits,
+// every term is expanded to its Persian and Arabic spellings
+func searchTerms(q string) (terms [][]string) {
+	for _, word := range strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		terms = append(terms, SimilarCharVariants(word))
+	}
+
+	return terms
+}
+
+// joinSearchTerms builds a search query, variants of a term are joined by or
+// and terms are joined by and
+func joinSearchTerms(terms [][]string, and, or string, format func(string) string) string {
+	groups := make([]string, len(terms))
+
+	for i, variants := range terms {
+		formatted := make([]string, len(variants))
+		for j, v := range variants {
+			formatted[j] = format(v)
+		}
+		groups[i] = "(" + strings.Join(formatted, or) + ")"
+	}
+
+	return strings.Join(groups, and)
+}
@@ -0,0 +1,140 @@
+package simutils
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+type searchTestItem struct {
+	Model
+	Title string `search:"4"`
+	Body  string `search:"1"`
+	Price int
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialector gorm.Dialector
+		q         string
+		want      []string
+	}{
+		{
+			name:      "postgres",
+			dialector: postgres.New(postgres.Config{DSN: "host=localhost"}),
+			q:         "book",
+			want:      []string{"@@ to_tsquery('simple', '(book:*)')", "setweight(to_tsvector('simple', coalesce(\"search_test_items\".\"title\", '')), 'A')", "ts_rank("},
+		},
+		{
+			name:      "sqlserver",
+			dialector: sqlserver.Open("sqlserver://localhost"),
+			q:         "book",
+			want:      []string{`CONTAINS(("search_test_items"."title", "search_test_items"."body"), '("book*")')`, "THEN 4 ELSE 0 END"},
+		},
+		{
+			name:      "mysql",
+			dialector: mysql.New(mysql.Config{DSN: "user@/db", SkipInitializeWithVersion: true}),
+			q:         "red book",
+			want:      []string{"MATCH(`search_test_items`.`title`,`search_test_items`.`body`) AGAINST('+(red*) +(book*)' IN BOOLEAN MODE)"},
+		},
+		{
+			name:      "sqlite with arabic and persian spellings",
+			dialector: sqlite.Open("file:search_test?mode=memory&cache=shared"),
+			q:         "كتاب",
+			want:      []string{"IN (SELECT rowid FROM `search_test_items_search` WHERE `search_test_items_search` MATCH", `كتاب""* OR ""کتاب""*`, "bm25(`search_test_items_search`, 4, 1)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gorm.Open(tt.dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var items []searchTestItem
+			stmt := Search(db.Model(&searchTestItem{}), &searchTestItem{}, tt.q).Find(&items).Statement
+			sql := db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...)
+			for _, want := range tt.want {
+				if !strings.Contains(strings.ReplaceAll(sql, ", ", ","), strings.ReplaceAll(want, ", ", ",")) {
+					t.Errorf("Search() sql = %s, want %s", sql, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearch_NoTerm(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:search_no_term_test?mode=memory&cache=shared"), &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Search(db.Model(&listTestItem{}), &listTestItem{}, " ").Error; err != nil {
+		t.Errorf("Search() error = %v, want nil", err)
+	}
+	if err := Search(db.Model(&listTestItem{}), &listTestItem{}, "book").Error; err != ErrNoSearchField {
+		t.Errorf("Search() error = %v, want %v", err, ErrNoSearchField)
+	}
+}
+
+func TestSearch_SQLite(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:search_sqlite_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	if err := db.AutoMigrate(&searchTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateSearchIndex(db, &searchTestItem{}); err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			t.Skip("go-sqlite3 is built without the sqlite_fts5 tag")
+		}
+		t.Fatal(err)
+	}
+	if err := db.Create([]*searchTestItem{
+		{Title: "كتاب عربي", Body: "paper"},
+		{Title: "کتاب فارسی", Body: "paper"},
+		{Title: "دفتر", Body: "کتاب"},
+		{Title: "قلم", Body: "ink"},
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		q    string
+		want []string
+	}{
+		{"persian spelling", "کتاب", []string{"کتاب فارسی", "كتاب عربي", "دفتر"}},
+		{"arabic spelling", "كتاب", []string{"كتاب عربي", "کتاب فارسی", "دفتر"}},
+		{"prefix and terms", "کتا فارسی", []string{"کتاب فارسی"}},
+		{"no match", "ink paper", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var items []*searchTestItem
+			if err := Search(db.Model(&searchTestItem{}), &searchTestItem{}, tt.q).Find(&items).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(items))
+			for i, item := range items {
+				got[i] = item.Title
+			}
+			slices.Sort(got)
+			slices.Sort(tt.want)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package simutils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrSeedFormat        = errors.New("unsupported seed format")
+	ErrSeedUnknownColumn = errors.New("seed column has no field")
+	ErrSeedReference     = errors.New("seed reference is not found")
+)
+
+// SeedFormat is the format of a fixture
+type SeedFormat string
+
+const (
+	SeedJSON SeedFormat = "json"
+	SeedCSV  SeedFormat = "csv"
+	SeedXLSX SeedFormat = "xlsx"
+)
+
+// SeedOptions configures Seed
+type SeedOptions struct {
+	// Format of fixture, it is detected by file extension in SeedFile and SeedFS
+	Format SeedFormat
+	// Sheet of xlsx file, the first sheet if it is empty
+	Sheet string
+	// Keys are the natural key fields of upsert, the primary key is used if it is empty
+	Keys []string
+}
+
+// SeedRowError is the error of a fixture row, rows are numbered from 1 without header
+type SeedRowError struct {
+	Row int
+	Err error
+}
+
+func (e SeedRowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e SeedRowError) Unwrap() error {
+	return e.Err
+}
+
+// SeedResult is the result of Seed
+type SeedResult struct {
+	Created int
+	Updated int
+	Errors  []SeedRowError
+}
+
+// SeedFile seeds T from a JSON, CSV or XLSX file, see Seed
+func SeedFile[T any](db *gorm.DB, path string, opts SeedOptions) (*SeedResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if opts.Format == "" {
+		opts.Format = SeedFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."))
+	}
+
+	return Seed[T](db, f, opts)
+}
+
+// SeedFS seeds T from a fixture of fsys like embedded files of migrations, see Seed
+func SeedFS[T any](db *gorm.DB, fsys fs.FS, name string, opts SeedOptions) (*SeedResult, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if opts.Format == "" {
+		opts.Format = SeedFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), "."))
+	}
+
+	return Seed[T](db, f, opts)
+}
+
+// Seed upserts the rows of a fixture into the table of T.
+// JSON fixtures are arrays of objects, CSV and XLSX fixtures have a header row.
+// Columns are mapped to fields by `seed` tag, json name, column or field name.
+// Columns of belongs to associations are resolved by the Slug of the referenced record
+// or by its TKey like `owners:1`.
+// Existing records are found by opts.Keys or the primary key and only the columns of fixture are updated.
+// Row errors are collected in the result and the other rows are seeded.
+func Seed[T any](db *gorm.DB, r io.Reader, opts SeedOptions) (*SeedResult, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+
+	rows, err := readSeedRows(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SeedResult{}
+	for i, row := range rows {
+		created, err := seedRow[T](db, stmt.Schema, row, opts.Keys)
+		if err != nil {
+			result.Errors = append(result.Errors, SeedRowError{Row: i + 1, Err: err})
+		} else if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	return result, nil
+}
+
+// seedRow upserts a row in a savepoint and reports whether it is created
+func seedRow[T any](db *gorm.DB, sch *schema.Schema, row map[string]any, keys []string) (created bool, err error) {
+	var (
+		item    = new(T)
+		rv      = reflect.ValueOf(item).Elem()
+		ctx     = db.Statement.Context
+		columns []string
+	)
+
+	for name, value := range row {
+		if value == nil || value == "" {
+			continue
+		}
+
+		if f := seedField(sch, name); f != nil {
+			if err := setSeedValue(db, f, rv, value); err != nil {
+				return false, fmt.Errorf("%s: %w", name, err)
+			}
+			columns = append(columns, f.DBName)
+		} else if rel := lookUpSchemaRelation(sch, name); rel != nil {
+			cols, err := resolveSeedReference(db, rel, rv, cast.ToString(value))
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", name, err)
+			}
+			columns = append(columns, cols...)
+		} else {
+			return false, fmt.Errorf("%w: %s", ErrSeedUnknownColumn, name)
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(new(T))
+		if len(keys) == 0 {
+			for _, f := range sch.PrimaryFields {
+				v, zero := f.ValueOf(ctx, rv)
+				if zero {
+					created = true
+					return tx.Create(item).Error
+				}
+				query = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: v})
+			}
+		} else {
+			for _, key := range keys {
+				f := seedField(sch, key)
+				if f == nil {
+					return fmt.Errorf("%w: %s", ErrSeedUnknownColumn, key)
+				}
+				v, _ := f.ValueOf(ctx, rv)
+				query = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: v})
+			}
+		}
+
+		existing := new(T)
+		if res := query.Limit(1).Find(existing); res.Error != nil {
+			return res.Error
+		} else if res.RowsAffected == 0 {
+			created = true
+			return tx.Create(item).Error
+		}
+
+		// the primary key of existing record
+		erv := reflect.ValueOf(existing).Elem()
+		for _, f := range sch.PrimaryFields {
+			v, _ := f.ValueOf(ctx, erv)
+			if err := f.Set(ctx, rv, v); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(item).Select(columns).Updates(item).Error
+	})
+
+	return created, TranslateGormError(err)
+}
+
+// seedField finds the field of a fixture column by `seed` tag, json name, column or field name
+func seedField(sch *schema.Schema, name string) *schema.Field {
+	for _, f := range sch.Fields {
+		if f.StructField.Tag.Get("seed") == name {
+			return f
+		}
+	}
+
+	if f := lookUpSchemaField(sch, name); f != nil && f.DBName != "" {
+		return f
+	}
+
+	return nil
+}
+
+// setSeedValue sets the field of rv, texts of fixtures are converted to numbers and booleans
+func setSeedValue(db *gorm.DB, f *schema.Field, rv reflect.Value, value any) (err error) {
+	ft := f.IndirectFieldType
+
+	switch ft.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err = cast.ToInt64E(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err = cast.ToUint64E(value)
+	case reflect.Float32, reflect.Float64:
+		value, err = cast.ToFloat64E(value)
+	case reflect.Bool:
+		value, err = cast.ToBoolE(value)
+	default:
+		// texts, times and scanners are converted by gorm
+		return f.Set(db.Statement.Context, rv, value)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	converted := reflect.ValueOf(value).Convert(ft)
+	if f.FieldType.Kind() == reflect.Ptr {
+		ptr := reflect.New(ft)
+		ptr.Elem().Set(converted)
+		converted = ptr
+	}
+
+	return f.Set(db.Statement.Context, rv, converted.Interface())
+}
+
+// resolveSeedReference sets the foreign key of a belongs to association by the slug or TKey of referenced record
+// and returns the foreign key columns
+func resolveSeedReference(db *gorm.DB, rel *schema.Relationship, rv reflect.Value, value string) ([]string, error) {
+	if rel.Type != schema.BelongsTo || len(rel.References) != 1 {
+		return nil, ErrSeedReference
+	}
+
+	var (
+		ref = rel.References[0]
+		id  any
+	)
+
+	if table, key, ok := strings.Cut(value, ":"); ok && TKey(value).IsValid() {
+		if table != rel.FieldSchema.Table && table != GetModelName(reflect.New(rel.FieldSchema.ModelType).Interface()) {
+			return nil, ErrSeedReference
+		}
+		id = key
+	} else {
+		slug := rel.FieldSchema.LookUpField("Slug")
+		if slug == nil {
+			return nil, ErrSeedReference
+		}
+
+		var ids []any
+		if err := db.Session(&gorm.Session{NewDB: true}).Table(rel.FieldSchema.Table).
+			Where(clause.Eq{Column: clause.Column{Name: slug.DBName}, Value: value}).
+			Limit(1).Pluck(ref.PrimaryKey.DBName, &ids).Error; err != nil {
+			return nil, err
+		} else if len(ids) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrSeedReference, value)
+		}
+		id = ids[0]
+	}
+
+	if err := setSeedValue(db, ref.ForeignKey, rv, id); err != nil {
+		return nil, err
+	}
+
+	return []string{ref.ForeignKey.DBName}, nil
+}
+
+// readSeedRows reads the rows of fixture as maps of column to value
+func readSeedRows(r io.Reader, opts SeedOptions) ([]map[string]any, error) {
+	var records [][]string
+
+	switch opts.Format {
+	case SeedJSON:
+		var rows []map[string]any
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		if err := dec.Decode(&rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	case SeedCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		all, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		records = all
+	case SeedXLSX:
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		sheet := opts.Sheet
+		if sheet == "" {
+			sheet = f.GetSheetName(0)
+		}
+
+		if records, err = f.GetRows(sheet); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrSeedFormat, opts.Format)
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	var (
+		header = records[0]
+		rows   = make([]map[string]any, 0, len(records)-1)
+	)
+
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	for _, record := range records[1:] {
+		row := make(map[string]any, len(header))
+		for i, name := range header {
+			if i < len(record) && name != "" {
+				row[name] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
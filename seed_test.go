@@ -0,0 +1,117 @@
+package simutils
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+type seedTestOwner struct {
+	Model
+	Slug Slug `gorm:"unique"`
+}
+
+type seedTestItem struct {
+	Model
+	Code    string `seed:"code" gorm:"unique"`
+	Name    string `json:"name"`
+	Price   int
+	Active  NullBool
+	OwnerID PID
+	Owner   *seedTestOwner
+}
+
+func TestSeed(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:seed_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+
+	if err := db.AutoMigrate(&seedTestOwner{}, &seedTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&[]seedTestOwner{{Model: Model{ID: 1}, Slug: "ali"}, {Model: Model{ID: 2}, Slug: "reza"}})
+
+	xlsx := excelize.NewFile()
+	for i, row := range [][]any{{"code", "Price"}, {"pen", 30}, {"cup", 5}} {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		xlsx.SetSheetRow("Sheet1", cell, &row)
+	}
+	var xlsxData bytes.Buffer
+	if _, err := xlsx.WriteTo(&xlsxData); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		data        string
+		opts        SeedOptions
+		wantCreated int
+		wantUpdated int
+		wantErrRows []int
+	}{
+		{
+			name:        "json with slug reference",
+			data:        `[{"id": 1, "code": "pen", "name": "Pen", "Price": 10, "active": true, "owner": "ali"}, {"id": 2, "code": "book", "name": "Book", "owner": "nobody"}]`,
+			opts:        SeedOptions{Format: SeedJSON},
+			wantCreated: 1,
+			wantErrRows: []int{2},
+		},
+		{
+			name:        "csv by natural key with tkey reference",
+			data:        "code,name,Owner,price\npen,Blue Pen,seed_test_owners:2,\nbook,Book,,abc\nbag,Bag,,7\n",
+			opts:        SeedOptions{Format: SeedCSV, Keys: []string{"code"}},
+			wantCreated: 1,
+			wantUpdated: 1,
+			wantErrRows: []int{2},
+		},
+		{
+			name:        "xlsx",
+			data:        xlsxData.String(),
+			opts:        SeedOptions{Format: SeedXLSX, Keys: []string{"code"}},
+			wantCreated: 1,
+			wantUpdated: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Seed[seedTestItem](db, strings.NewReader(tt.data), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var errRows []int
+			for _, e := range got.Errors {
+				errRows = append(errRows, e.Row)
+			}
+			if got.Created != tt.wantCreated || got.Updated != tt.wantUpdated || len(errRows) != len(tt.wantErrRows) {
+				t.Errorf("Seed() = %+v, want created %d, updated %d, error rows %v", got, tt.wantCreated, tt.wantUpdated, tt.wantErrRows)
+			}
+			for i := range errRows {
+				if i < len(tt.wantErrRows) && errRows[i] != tt.wantErrRows[i] {
+					t.Errorf("Seed() error rows = %v, want %v", errRows, tt.wantErrRows)
+				}
+			}
+		})
+	}
+
+	var pen seedTestItem
+	db.Where("code = ?", "pen").First(&pen)
+	if pen.ID != 1 || pen.Name != "Blue Pen" || pen.Price != 30 || pen.OwnerID != 2 || !pen.Active.Bool {
+		t.Errorf("Seed() pen = %+v", pen)
+	}
+
+	got, err := Seed[seedTestItem](db, strings.NewReader(`[{"secret": 1}]`), SeedOptions{Format: SeedJSON})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(got.Errors) != 1 || !errors.Is(got.Errors[0], ErrSeedUnknownColumn) {
+		t.Errorf("Seed() errors = %v, want %v", got.Errors, ErrSeedUnknownColumn)
+	}
+	if _, err := Seed[seedTestItem](db, strings.NewReader(""), SeedOptions{Format: "yaml"}); !errors.Is(err, ErrSeedFormat) {
+		t.Errorf("Seed() error = %v, want %v", err, ErrSeedFormat)
+	}
+}
@@ -0,0 +1,64 @@
+package simmigrate
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+)
+
+// sqlFileRegex defines the regex pattern of migration files like 0001_create_users.up.sql
+var sqlFileRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// AddFS adds the SQL migrations of dir in fsys to package name, like embedded files:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+// Every file may contain several statements, mysql needs `multiStatements=true` in DSN.
+func (m *Migrator) AddFS(name string, fsys fs.FS, dir string, dependsOn ...string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	var (
+		versions   []string
+		migrations = map[string]*Migration{}
+	)
+
+	for _, entry := range entries {
+		matches := sqlFileRegex.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		version := matches[1]
+		mig, ok := migrations[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: matches[2]}
+			migrations[version] = mig
+			versions = append(versions, version)
+		} else if mig.Name != matches[2] {
+			return fmt.Errorf("%w: %s %s", ErrDuplicateVersion, name, version)
+		}
+
+		if matches[3] == "up" {
+			mig.UpSQL = string(b)
+		} else {
+			mig.DownSQL = string(b)
+		}
+	}
+
+	list := make([]Migration, len(versions))
+	for i, v := range versions {
+		list[i] = *migrations[v]
+	}
+
+	return m.Add(name, dependsOn, list...).Error()
+}
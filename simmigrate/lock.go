@@ -0,0 +1,156 @@
+package simmigrate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"gorm.io/gorm"
+
+	simutils "github.com/alifakhimi/simple-utils-go"
+)
+
+var (
+	ErrLockNotAcquired = errors.New("migration lock is not acquired")
+
+	// LockRetryInterval is the delay between tries of lock table
+	LockRetryInterval = 100 * time.Millisecond
+	// LockStaleTimeout is the age after which a lock of TableLock is broken,
+	// it must be longer than the migrations
+	LockStaleTimeout = 15 * time.Minute
+)
+
+// Locker prevents concurrent instances from running migrations at the same time.
+// Lock and Unlock are called on the same connection.
+type Locker interface {
+	Lock(conn *gorm.DB) error
+	Unlock(conn *gorm.DB) error
+}
+
+// lockerOf returns the lock of the driver of db named name
+func lockerOf(db *gorm.DB, name string) Locker {
+	switch simutils.DriverOf(db) {
+	case simutils.PostgresSQL:
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(name))
+		return &postgresLock{key: int64(h.Sum64())}
+	case simutils.MySQL:
+		return &mysqlLock{name: name}
+	case simutils.SQLServer:
+		return &sqlserverLock{name: name}
+	}
+
+	return &TableLock{Table: name}
+}
+
+// postgresLock is a session level advisory lock
+type postgresLock struct {
+	key int64
+}
+
+func (l *postgresLock) Lock(conn *gorm.DB) error {
+	return conn.Exec("SELECT pg_advisory_lock(?)", l.key).Error
+}
+
+func (l *postgresLock) Unlock(conn *gorm.DB) error {
+	return conn.Exec("SELECT pg_advisory_unlock(?)", l.key).Error
+}
+
+// mysqlLock is a named lock of GET_LOCK
+type mysqlLock struct {
+	name string
+}
+
+func (l *mysqlLock) Lock(conn *gorm.DB) error {
+	var acquired int
+	if err := conn.Raw("SELECT GET_LOCK(?, -1)", l.name).Scan(&acquired).Error; err != nil {
+		return err
+	} else if acquired != 1 {
+		return ErrLockNotAcquired
+	}
+
+	return nil
+}
+
+func (l *mysqlLock) Unlock(conn *gorm.DB) error {
+	return conn.Exec("SELECT RELEASE_LOCK(?)", l.name).Error
+}
+
+// sqlserverLock is a session application lock of sp_getapplock
+type sqlserverLock struct {
+	name string
+}
+
+func (l *sqlserverLock) Lock(conn *gorm.DB) error {
+	var result int
+	if err := conn.Raw("DECLARE @result int; EXEC @result = sp_getapplock @Resource = ?, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = -1; SELECT @result", l.name).
+		Scan(&result).Error; err != nil {
+		return err
+	} else if result < 0 {
+		return ErrLockNotAcquired
+	}
+
+	return nil
+}
+
+func (l *sqlserverLock) Unlock(conn *gorm.DB) error {
+	return conn.Exec("EXEC sp_releaseapplock @Resource = ?, @LockOwner = 'Session'", l.name).Error
+}
+
+// TableLock is a lock of a row in Table, it is used by databases without advisory locks.
+// Lock waits until the row is deleted by the other instance or it is older than Timeout,
+// which is LockStaleTimeout if it is zero, so the lock of a crashed instance is broken.
+type TableLock struct {
+	Table   string
+	Timeout time.Duration
+
+	owner string
+}
+
+func (l *TableLock) Lock(conn *gorm.DB) error {
+	table := conn.Statement.Quote(l.Table)
+
+	if err := conn.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY, owner VARCHAR(64) NOT NULL, locked_at TIMESTAMP NOT NULL)", table)).Error; err != nil {
+		return err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	owner := hex.EncodeToString(b)
+
+	for {
+		now := time.Now().UTC()
+		if err := conn.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = 1 AND locked_at < ?", table), now.Add(-simutils.DefaultIfZero(l.Timeout, LockStaleTimeout))).Error; err != nil {
+			return err
+		}
+
+		err := conn.Exec(fmt.Sprintf("INSERT INTO %s (id, owner, locked_at) VALUES (1, ?, ?)", table), owner, now).Error
+		if err == nil {
+			l.owner = owner
+			return nil
+		} else if !errors.Is(simutils.TranslateGormError(err), simutils.ErrAlreadyExist) {
+			return err
+		}
+
+		select {
+		case <-time.After(LockRetryInterval):
+		case <-conn.Statement.Context.Done():
+			return conn.Statement.Context.Err()
+		}
+	}
+}
+
+// Unlock deletes the row of lock if it is not broken and taken by another instance
+func (l *TableLock) Unlock(conn *gorm.DB) error {
+	return conn.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = 1 AND owner = ?", conn.Statement.Quote(l.Table)), l.owner).Error
+}
+
+// ForceUnlock deletes the row of lock whoever holds it
+func (l *TableLock) ForceUnlock(conn *gorm.DB) error {
+	return conn.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = 1", conn.Statement.Quote(l.Table))).Error
+}
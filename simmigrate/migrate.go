@@ -0,0 +1,562 @@
+/*
+Package simmigrate provides versioned database migrations for simregistrar packages.
+
+Key Features:
+1. **Versioned migrations**:
+  - Go migrations with Up/Down functions and embedded SQL files
+    named `<version>_<name>.up.sql` and `<version>_<name>.down.sql`.
+  - Versions are numbers and migrations are ordered numerically.
+  - Applied versions are stored in the `schema_migrations` table.
+
+2. **Package ordering**:
+  - Packages are migrated after the packages they depend on.
+
+3. **Dry run**:
+  - Prints the SQL of pending migrations without running them,
+    enabled by `DBConfig.DryRun` or WithDryRun.
+
+4. **Locking**:
+  - Concurrent instances wait for each other by advisory locks on postgres, mysql and sqlserver
+    and a lock table on sqlite, which is broken after LockStaleTimeout.
+
+Usage:
+
+	m := simmigrate.New(db).
+		Add("users", nil, simmigrate.Migration{Version: "0001", Name: "create_users", Up: createUsers, Down: dropUsers})
+	if err := m.AddFS("orders", migrations, "migrations", "users"); err != nil {
+		return err
+	}
+	err := m.Up(ctx)
+
+Packages of simregistrar which implement Package are added by AddPackages:
+
+	err := simmigrate.New(db).AddPackages(simregistrar.All()...).Up(ctx)
+*/
+package simmigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/alifakhimi/simple-utils-go/simregistrar"
+)
+
+var (
+	ErrDuplicateVersion  = errors.New("migration version already exists")
+	ErrInvalidMigration  = errors.New("migration has no up")
+	ErrInvalidVersion    = errors.New("migration version is not a number")
+	ErrUnknownDependency = errors.New("package depends on an unknown package")
+	ErrDependencyCycle   = errors.New("package dependencies have a cycle")
+	ErrIrreversible      = errors.New("migration has no down")
+)
+
+// versionRegex defines the regex pattern of migration versions
+var versionRegex = regexp.MustCompile(`^\d+$`)
+
+// DefaultTable is the table of applied migrations
+const DefaultTable = "schema_migrations"
+
+// Migration is a versioned change of database schema or data.
+// Up and Down run in a transaction, UpSQL and DownSQL are used if they are nil.
+type Migration struct {
+	// Version is a number which orders the migrations of a package, like 0001 or 20240101120000
+	Version string
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+	UpSQL   string
+	DownSQL string
+
+	pkg string
+}
+
+// Package is a simregistrar package with versioned migrations
+type Package interface {
+	simregistrar.Package
+	Migrations() []Migration
+}
+
+// Dependent packages are migrated after the packages returned by DependsOn
+type Dependent interface {
+	DependsOn() []string
+}
+
+// Status is the state of a migration
+type Status struct {
+	Package   string
+	Version   string
+	Name      string
+	AppliedAt *time.Time
+}
+
+// record is a row of migrations table
+type record struct {
+	Package   string    `gorm:"primaryKey;size:128"`
+	Version   string    `gorm:"primaryKey;size:128"`
+	Name      string    `gorm:"size:256"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+type pkg struct {
+	name       string
+	dependsOn  []string
+	migrations []Migration
+}
+
+// Migrator runs the migrations of packages
+type Migrator struct {
+	db       *gorm.DB
+	table    string
+	dryRun   bool
+	out      io.Writer
+	locker   Locker
+	packages map[string]*pkg
+	err      error
+}
+
+// Option configures Migrator
+type Option func(*Migrator)
+
+// WithTable sets the table of applied migrations
+func WithTable(table string) Option {
+	return func(m *Migrator) { m.table = table }
+}
+
+// WithDryRun prints the SQL of pending migrations to out instead of running them
+func WithDryRun(out io.Writer) Option {
+	return func(m *Migrator) {
+		m.dryRun = true
+		m.out = out
+	}
+}
+
+// WithLocker replaces the lock of driver
+func WithLocker(locker Locker) Option {
+	return func(m *Migrator) { m.locker = locker }
+}
+
+// New returns a migrator of db, dry run is enabled if db is in dry run mode
+func New(db *gorm.DB, opts ...Option) *Migrator {
+	m := &Migrator{
+		db:       db,
+		table:    DefaultTable,
+		dryRun:   db.DryRun,
+		out:      os.Stdout,
+		packages: map[string]*pkg{},
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.locker == nil {
+		m.locker = lockerOf(db, m.table+"_lock")
+	}
+
+	return m
+}
+
+// Error returns the error of adding migrations
+func (m *Migrator) Error() error {
+	return m.err
+}
+
+// Add adds migrations of package name which depends on dependsOn packages
+func (m *Migrator) Add(name string, dependsOn []string, migrations ...Migration) *Migrator {
+	if m.err != nil {
+		return m
+	}
+
+	p, ok := m.packages[name]
+	if !ok {
+		p = &pkg{name: name}
+		m.packages[name] = p
+	}
+
+	for _, dep := range dependsOn {
+		if dep != name && !contains(p.dependsOn, dep) {
+			p.dependsOn = append(p.dependsOn, dep)
+		}
+	}
+
+	for _, mig := range migrations {
+		if mig.Up == nil && mig.UpSQL == "" {
+			m.err = fmt.Errorf("%w: %s %s", ErrInvalidMigration, name, mig.Version)
+			return m
+		}
+		if !versionRegex.MatchString(mig.Version) {
+			m.err = fmt.Errorf("%w: %s %s", ErrInvalidVersion, name, mig.Version)
+			return m
+		}
+
+		for _, existing := range p.migrations {
+			if compareVersions(existing.Version, mig.Version) == 0 {
+				m.err = fmt.Errorf("%w: %s %s", ErrDuplicateVersion, name, mig.Version)
+				return m
+			}
+		}
+
+		mig.pkg = name
+		p.migrations = append(p.migrations, mig)
+	}
+
+	sort.Slice(p.migrations, func(i, j int) bool { return compareVersions(p.migrations[i].Version, p.migrations[j].Version) < 0 })
+
+	return m
+}
+
+// AddPackages adds the migrations of registrar packages which implement Package
+func (m *Migrator) AddPackages(pkgs ...simregistrar.Package) *Migrator {
+	for _, p := range pkgs {
+		mp, ok := p.(Package)
+		if !ok {
+			continue
+		}
+
+		var deps []string
+		if d, ok := p.(Dependent); ok {
+			deps = d.DependsOn()
+		}
+
+		m.Add(p.Name(), deps, mp.Migrations()...)
+	}
+
+	return m
+}
+
+// Plan returns the pending migrations in the order they run
+func (m *Migrator) Plan(ctx context.Context) ([]Migration, error) {
+	order, err := m.order()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, p := range order {
+		for _, mig := range p.migrations {
+			if _, ok := applied[p.name][mig.Version]; !ok {
+				pending = append(pending, mig)
+			}
+		}
+	}
+
+	return pending, nil
+}
+
+// Status returns the state of all migrations in the order they run
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	order, err := m.order()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, p := range order {
+		for _, mig := range p.migrations {
+			s := Status{Package: p.name, Version: mig.Version, Name: mig.Name}
+			if r, ok := applied[p.name][mig.Version]; ok {
+				s.AppliedAt = &r.AppliedAt
+			}
+			statuses = append(statuses, s)
+		}
+	}
+
+	return statuses, nil
+}
+
+// Up runs the pending migrations, every migration runs in its own transaction
+func (m *Migrator) Up(ctx context.Context) error {
+	if m.dryRun {
+		pending, err := m.Plan(ctx)
+		if err != nil {
+			return err
+		}
+
+		return m.print(ctx, pending, true)
+	}
+
+	// pending migrations are read after lock, another instance may have migrated them
+	return m.withLock(ctx, func(conn *gorm.DB) error {
+		pending, err := m.Plan(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range pending {
+			if err := m.run(conn, mig, true); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the last steps applied migrations of package name
+func (m *Migrator) Down(ctx context.Context, name string, steps int) error {
+	if m.dryRun {
+		reverts, err := m.reverts(ctx, name, steps)
+		if err != nil {
+			return err
+		}
+
+		return m.print(ctx, reverts, false)
+	}
+
+	return m.withLock(ctx, func(conn *gorm.DB) error {
+		reverts, err := m.reverts(ctx, name, steps)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range reverts {
+			if err := m.run(conn, mig, false); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// reverts returns the last steps applied migrations of package name, the latest first
+func (m *Migrator) reverts(ctx context.Context, name string, steps int) ([]Migration, error) {
+	p, ok := m.packages[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDependency, name)
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverts []Migration
+	for i := len(p.migrations) - 1; i >= 0 && len(reverts) < steps; i-- {
+		mig := p.migrations[i]
+		if _, ok := applied[name][mig.Version]; !ok {
+			continue
+		}
+
+		if mig.Down == nil && mig.DownSQL == "" {
+			return nil, fmt.Errorf("%w: %s %s", ErrIrreversible, name, mig.Version)
+		}
+
+		reverts = append(reverts, mig)
+	}
+
+	return reverts, nil
+}
+
+// run runs up or down of mig and records it in one transaction
+func (m *Migrator) run(conn *gorm.DB, mig Migration, up bool) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := mig.exec(tx, up); err != nil {
+			return fmt.Errorf("migration %s %s_%s: %w", mig.pkg, mig.Version, mig.Name, err)
+		}
+
+		if up {
+			return tx.Table(m.table).Create(&record{Package: mig.pkg, Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+		}
+
+		return tx.Table(m.table).Where("package = ? AND version = ?", mig.pkg, mig.Version).Delete(&record{}).Error
+	})
+}
+
+func (mig Migration) exec(tx *gorm.DB, up bool) error {
+	switch {
+	case up && mig.Up != nil:
+		return mig.Up(tx)
+	case up:
+		return tx.Exec(mig.UpSQL).Error
+	case mig.Down != nil:
+		return mig.Down(tx)
+	default:
+		return tx.Exec(mig.DownSQL).Error
+	}
+}
+
+// print writes the SQL of migrations without running them
+func (m *Migrator) print(ctx context.Context, migrations []Migration, up bool) error {
+	tx := m.db.Session(&gorm.Session{DryRun: true, Context: ctx, Logger: &sqlPrinter{out: m.out}})
+
+	for _, mig := range migrations {
+		direction := "up"
+		if !up {
+			direction = "down"
+		}
+
+		if _, err := fmt.Fprintf(m.out, "-- %s %s_%s %s\n", mig.pkg, mig.Version, mig.Name, direction); err != nil {
+			return err
+		}
+
+		if err := mig.exec(tx, up); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applied returns the applied migrations by package and version
+func (m *Migrator) applied(ctx context.Context) (map[string]map[string]record, error) {
+	// applied versions are read in dry run too
+	db := m.db.Session(&gorm.Session{Context: ctx, NewDB: true})
+	db.Config.DryRun = false
+
+	applied := map[string]map[string]record{}
+
+	if !db.Migrator().HasTable(m.table) {
+		if m.dryRun {
+			return applied, nil
+		}
+
+		if err := db.Table(m.table).AutoMigrate(&record{}); err != nil {
+			return nil, err
+		}
+	}
+
+	var records []record
+	if err := db.Table(m.table).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		if applied[r.Package] == nil {
+			applied[r.Package] = map[string]record{}
+		}
+		applied[r.Package][r.Version] = r
+	}
+
+	return applied, nil
+}
+
+// order sorts packages topologically by their dependencies, independent packages are sorted by name
+func (m *Migrator) order() ([]*pkg, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var (
+		names    = make([]string, 0, len(m.packages))
+		order    []*pkg
+		visiting = map[string]bool{}
+		visited  = map[string]bool{}
+		visit    func(name string) error
+	)
+
+	for name := range m.packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		} else if visiting[name] {
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, name)
+		}
+
+		p, ok := m.packages[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownDependency, name)
+		}
+
+		visiting[name] = true
+		deps := append([]string{}, p.dependsOn...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		visiting[name], visited[name] = false, true
+
+		order = append(order, p)
+
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// withLock runs fn on a single connection holding the migration lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) (err error) {
+		// statements must not be shared between calls
+		conn = conn.Session(&gorm.Session{})
+
+		if err = m.locker.Lock(conn); err != nil {
+			return err
+		}
+
+		defer func() {
+			if unlockErr := m.locker.Unlock(conn); err == nil {
+				err = unlockErr
+			}
+		}()
+
+		return fn(conn)
+	})
+}
+
+// sqlPrinter is a gorm logger which writes the SQL of statements
+type sqlPrinter struct {
+	out io.Writer
+}
+
+func (p *sqlPrinter) LogMode(logger.LogLevel) logger.Interface      { return p }
+func (p *sqlPrinter) Info(context.Context, string, ...interface{})  {}
+func (p *sqlPrinter) Warn(context.Context, string, ...interface{})  {}
+func (p *sqlPrinter) Error(context.Context, string, ...interface{}) {}
+func (p *sqlPrinter) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	if sql, _ := fc(); sql != "" {
+		fmt.Fprintf(p.out, "%s;\n", sql)
+	}
+}
+
+// compareVersions compares the numbers of versions a and b of any length
+func compareVersions(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+
+	return strings.Compare(a, b)
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,257 @@
+package simmigrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorm.io/gorm"
+
+	simutils "github.com/alifakhimi/simple-utils-go"
+)
+
+type testPackage struct {
+	name string
+	deps []string
+	migs []Migration
+}
+
+func (p testPackage) Init() error             { return nil }
+func (p testPackage) Name() string            { return p.name }
+func (p testPackage) Migrator() error         { return nil }
+func (p testPackage) Error() error            { return nil }
+func (p testPackage) DependsOn() []string     { return p.deps }
+func (p testPackage) Migrations() []Migration { return p.migs }
+
+func connect(t *testing.T, name string, dryRun bool) *gorm.DB {
+	dbConn := &simutils.DBConnection{DBConfig: simutils.DBConfig{Driver: simutils.SQLite, DSN: "file:" + name + "?mode=memory&cache=shared", DryRun: dryRun}}
+	if err := simutils.Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	return dbConn.DB
+}
+
+func TestMigrator(t *testing.T) {
+	db := connect(t, "migrate_test", false)
+
+	var ran []string
+	goMigration := func(version string) Migration {
+		return Migration{
+			Version: version,
+			Name:    "go",
+			Up:      func(tx *gorm.DB) error { ran = append(ran, "orders:"+version); return nil },
+			Down:    func(tx *gorm.DB) error { ran = append(ran, "orders:-"+version); return nil },
+		}
+	}
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"migrations/0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT")},
+	}
+
+	m := New(db).AddPackages(testPackage{name: "orders", deps: []string{"users"}, migs: []Migration{goMigration("0002"), goMigration("0001")}})
+	if err := m.AddFS("users", fsys, "migrations"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ran, ","); got != "orders:0001,orders:0002" {
+		t.Errorf("Up() ran = %s", got)
+	}
+	if !db.Migrator().HasColumn("users", "email") {
+		t.Errorf("Up() users.email is not created")
+	}
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("Status() %s %s is not applied", s.Package, s.Version)
+		}
+		order = append(order, s.Package+":"+s.Version)
+	}
+	if got := strings.Join(order, ","); got != "users:0001,users:0002,orders:0001,orders:0002" {
+		t.Errorf("Status() order = %s", got)
+	}
+
+	// applied migrations do not run again
+	ran = nil
+	if err := m.Up(context.Background()); err != nil || len(ran) != 0 {
+		t.Errorf("Up() error = %v, ran = %v", err, ran)
+	}
+
+	if err := m.Down(context.Background(), "orders", 1); err != nil || strings.Join(ran, ",") != "orders:-0002" {
+		t.Errorf("Down() error = %v, ran = %v", err, ran)
+	}
+	if err := m.Down(context.Background(), "users", 1); !errors.Is(err, ErrIrreversible) {
+		t.Errorf("Down() error = %v, want %v", err, ErrIrreversible)
+	}
+
+	pending, err := m.Plan(context.Background())
+	if err != nil || len(pending) != 1 || pending[0].Version != "0002" {
+		t.Errorf("Plan() = %v, %v", pending, err)
+	}
+}
+
+func TestMigrator_DryRun(t *testing.T) {
+	db := connect(t, "migrate_dry_run_test", true)
+
+	var out bytes.Buffer
+	m := New(db, WithDryRun(&out)).Add("users", nil, Migration{
+		Version: "0001",
+		Name:    "create_users",
+		Up:      func(tx *gorm.DB) error { return tx.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY)").Error },
+	})
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := "-- users 0001_create_users up\nCREATE TABLE users (id INTEGER PRIMARY KEY);\n"; out.String() != want {
+		t.Errorf("Up() output = %q, want %q", out.String(), want)
+	}
+	live := db.Session(&gorm.Session{})
+	live.Config.DryRun = false
+	if live.Migrator().HasTable("users") {
+		t.Errorf("Up() dry run created table")
+	}
+}
+
+func TestMigrator_order(t *testing.T) {
+	tests := []struct {
+		name    string
+		deps    map[string][]string
+		want    string
+		wantErr error
+	}{
+		{name: "dependencies first", deps: map[string][]string{"c": {"b"}, "b": {"a"}, "a": nil}, want: "a,b,c"},
+		{name: "independent by name", deps: map[string][]string{"b": nil, "a": nil}, want: "a,b"},
+		{name: "cycle", deps: map[string][]string{"a": {"b"}, "b": {"a"}}, wantErr: ErrDependencyCycle},
+		{name: "unknown", deps: map[string][]string{"a": {"x"}}, wantErr: ErrUnknownDependency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{packages: map[string]*pkg{}}
+			for name, deps := range tt.deps {
+				m.Add(name, deps)
+			}
+
+			order, err := m.order()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("order() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var names []string
+			for _, p := range order {
+				names = append(names, p.name)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("order() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_Add(t *testing.T) {
+	up := func(tx *gorm.DB) error { return nil }
+
+	tests := []struct {
+		name     string
+		versions []string
+		want     string
+		wantErr  error
+	}{
+		{name: "numeric order", versions: []string{"10", "9", "0002", "100"}, want: "0002,9,10,100"},
+		{name: "timestamps", versions: []string{"20240201000000", "20240101120000"}, want: "20240101120000,20240201000000"},
+		{name: "same number", versions: []string{"1", "0001"}, wantErr: ErrDuplicateVersion},
+		{name: "not a number", versions: []string{"1a"}, wantErr: ErrInvalidVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Migrator{packages: map[string]*pkg{}}
+			for _, v := range tt.versions {
+				m.Add("a", nil, Migration{Version: v, Up: up})
+			}
+			if !errors.Is(m.Error(), tt.wantErr) {
+				t.Fatalf("Add() error = %v, want %v", m.Error(), tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			var versions []string
+			for _, mig := range m.packages["a"].migrations {
+				versions = append(versions, mig.Version)
+			}
+			if got := strings.Join(versions, ","); got != tt.want {
+				t.Errorf("Add() versions = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableLock(t *testing.T) {
+	db := connect(t, "migrate_lock_test", false)
+	lock := &TableLock{Table: "test_lock"}
+
+	if err := lock.Lock(db); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*LockRetryInterval)
+	defer cancel()
+	if err := lock.Lock(db.WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lock() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := lock.Unlock(db); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lock.Lock(db.WithContext(ctx)); err != nil {
+		t.Errorf("Lock() error = %v", err)
+	}
+}
+
+func TestTableLock_Stale(t *testing.T) {
+	db := connect(t, "migrate_stale_lock_test", false)
+	crashed := &TableLock{Table: "test_lock"}
+	lock := &TableLock{Table: "test_lock", Timeout: 2 * LockRetryInterval}
+
+	if err := crashed.Lock(db); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lock.Lock(db.WithContext(ctx)); err != nil {
+		t.Fatalf("Lock() of stale lock error = %v", err)
+	}
+
+	// the broken lock must not release the new one
+	if err := crashed.Unlock(db); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), LockRetryInterval)
+	defer cancel()
+	if err := crashed.Lock(db.WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lock() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := crashed.ForceUnlock(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.Lock(db); err != nil {
+		t.Errorf("Lock() after ForceUnlock() error = %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package simregistrar
 
 import (
 	"errors"
+	"sort"
 	"strings"
 )
 
@@ -20,6 +21,12 @@ type Registrar interface {
 	Error() error
 }
 
+// Lister is implemented by the registrars which can list their packages,
+// the Registrar of New implements it
+type Lister interface {
+	All() Packages
+}
+
 type reg struct {
 	packages map[string]Package
 	err      error
@@ -74,6 +81,19 @@ func (r *reg) Get(name string) (pkg Package, err error) {
 	}
 }
 
+// All returns the registered packages sorted by name
+func All() Packages { return registrar.All() }
+
+// All returns the registered packages sorted by name
+func (r *reg) All() Packages {
+	pkgs := make(Packages, 0, len(r.packages))
+	for _, p := range r.packages {
+		pkgs = append(pkgs, p)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name() < pkgs[j].Name() })
+	return pkgs
+}
+
 // Add adds package in registrar
 func Add(pkgs ...Package) Registrar { return registrar.Add(pkgs...) }
 
@@ -1,107 +1,74 @@
 package simutils
 
 import (
+	"strings"
+
 	valid "github.com/asaskevich/govalidator"
 )
 
-// Dict ...
-var DictPostgre = map[rune]string{
-	'آ': "(آ|ا|أ|إ|ع)",
-	'ا': "(آ|ا|أ|إ|ع)",
-	'أ': "(آ|ا|أ|إ|ع)",
-	'إ': "(آ|ا|أ|إ|ع)",
-	'ع': "(آ|ا|أ|إ|ع)",
-	'ک': "(ک|ك)",
-	'ك': "(ک|ك)",
-	'و': "(و|ؤ)",
-	'ؤ': "(و|ؤ)",
-	'ه': "(ه|ة|ح)",
-	'ة': "(ه|ة|ح)",
-	'ح': "(ه|ة|ح)",
-	'ی': "(ی|ي|ئ)",
-	'ي': "(ی|ي|ئ)",
-	'ئ': "(ی|ي|ئ)",
-	'ت': "(ت|ط)",
-	'ط': "(ت|ط)",
-	'ق': "(ق|غ)",
-	'غ': "(ق|غ)",
-	'ز': "(ز|ذ|ظ|ض)",
-	'ذ': "(ز|ذ|ظ|ض)",
-	'ظ': "(ز|ذ|ظ|ض)",
-	'ض': "(ز|ذ|ظ|ض)",
-	'س': "(س|ث|ص)",
-	'ث': "(س|ث|ص)",
-	'ص': "(س|ث|ص)",
-	'0': "(0|۰)",
-	'۰': "(0|۰)",
-	'1': "(1|۱)",
-	'۱': "(1|۱)",
-	'2': "(2|۲)",
-	'۲': "(2|۲)",
-	'3': "(3|۳)",
-	'۳': "(3|۳)",
-	'4': "(4|۴)",
-	'۴': "(4|۴)",
-	'5': "(5|۵)",
-	'۵': "(5|۵)",
-	'6': "(6|۶)",
-	'۶': "(6|۶)",
-	'7': "(7|۷)",
-	'۷': "(7|۷)",
-	'8': "(8|۸)",
-	'۸': "(8|۸)",
-	'9': "(9|۹)",
-	'۹': "(9|۹)",
+// similarCharGroups are the chars which are matched by each other in searches,
+// spellings of a group are written as its persian char in the Persian form and
+// the persian char is written as arabic in the Arabic form
+var similarCharGroups = []struct {
+	chars     string
+	persian   rune
+	arabic    rune
+	spellings string
+}{
+	{chars: "آاأإع", persian: 'ا', spellings: "أإ"},
+	{chars: "کك", persian: 'ک', arabic: 'ك', spellings: "ك"},
+	{chars: "وؤ", persian: 'و', spellings: "ؤ"},
+	{chars: "هةح", persian: 'ه', spellings: "ة"},
+	{chars: "یيئ", persian: 'ی', arabic: 'ي', spellings: "يئ"},
+	{chars: "تط"},
+	{chars: "قغ"},
+	{chars: "زذظض"},
+	{chars: "سثص"},
+	{chars: "0۰"},
+	{chars: "1۱"},
+	{chars: "2۲"},
+	{chars: "3۳"},
+	{chars: "4۴"},
+	{chars: "5۵"},
+	{chars: "6۶"},
+	{chars: "7۷"},
+	{chars: "8۸"},
+	{chars: "9۹"},
 }
 
 // Dict ...
-var DictSql = map[rune]string{
-	'آ': "[آاأإع]",
-	'ا': "[آاأإع]",
-	'أ': "[آاأإع]",
-	'إ': "[آاأإع]",
-	'ع': "[آاأإع]",
-	'ک': "[کك]",
-	'ك': "[کك]",
-	'و': "[وؤ]",
-	'ؤ': "[وؤ]",
-	'ه': "[هةح]",
-	'ة': "[هةح]",
-	'ح': "[هةح]",
-	'ی': "[یيئ]",
-	'ي': "[یيئ]",
-	'ئ': "[یيئ]",
-	'ت': "[تط]",
-	'ط': "[تط]",
-	'ق': "[قغ]",
-	'غ': "[قغ]",
-	'ز': "[زذظض]",
-	'ذ': "[زذظض]",
-	'ظ': "[زذظض]",
-	'ض': "[زذظض]",
-	'س': "[سثص]",
-	'ث': "[سثص]",
-	'ص': "[سثص]",
-	'0': "[0۰]",
-	'۰': "[0۰]",
-	'1': "[1۱]",
-	'۱': "[1۱]",
-	'2': "[2۲]",
-	'۲': "[2۲]",
-	'3': "[3۳]",
-	'۳': "[3۳]",
-	'4': "[4۴]",
-	'۴': "[4۴]",
-	'5': "[5۵]",
-	'۵': "[5۵]",
-	'6': "[6۶]",
-	'۶': "[6۶]",
-	'7': "[7۷]",
-	'۷': "[7۷]",
-	'8': "[8۸]",
-	'۸': "[8۸]",
-	'9': "[9۹]",
-	'۹': "[9۹]",
+var DictPostgre = similarCharDict("(", "|", ")")
+
+// Dict ...
+var DictSql = similarCharDict("[", "", "]")
+
+// similarCharForms converts a text to its Persian and Arabic forms
+var similarCharForms = func() []map[rune]rune {
+	persian, arabic := map[rune]rune{}, map[rune]rune{}
+	for _, g := range similarCharGroups {
+		for _, ch := range g.spellings {
+			persian[ch] = g.persian
+		}
+		if g.arabic != 0 {
+			arabic[g.persian] = g.arabic
+		}
+	}
+
+	return []map[rune]rune{persian, arabic}
+}()
+
+// similarCharDict maps every char of similarCharGroups to the pattern of its group
+func similarCharDict(open, sep, close string) map[rune]string {
+	dict := map[rune]string{}
+	for _, g := range similarCharGroups {
+		chars := strings.Split(g.chars, "")
+		pattern := open + strings.Join(chars, sep) + close
+		for _, ch := range g.chars {
+			dict[ch] = pattern
+		}
+	}
+
+	return dict
 }
 
 // ArabicPersianAI ...
@@ -148,3 +115,28 @@ func NeedCorrectChar(value interface{}) bool {
 
 	return false
 }
+
+// SimilarCharVariants returns the text with its Persian and Arabic spellings,
+// ASCII texts have no variants
+func SimilarCharVariants(s string) []string {
+	variants := []string{s}
+
+	if !NeedCorrectChar(s) {
+		return variants
+	}
+
+	for _, form := range similarCharForms {
+		v := []rune(s)
+		for i, ch := range v {
+			if r, ok := form[ch]; ok {
+				v[i] = r
+			}
+		}
+
+		if str := string(v); !ArrayElementExists(variants, str) {
+			variants = append(variants, str)
+		}
+	}
+
+	return variants
+}
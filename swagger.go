@@ -0,0 +1,216 @@
+package simutils
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/spec"
+	"github.com/swaggo/swag"
+	"gorm.io/gorm"
+)
+
+// SwaggerInstanceName is the swag instance served on /swagger/* of HttpServer.
+// It contains the swag document registered by the service (if any)
+// merged with the documents of registered resources.
+const SwaggerInstanceName = "simutils"
+
+var (
+	swaggerDocs = &swaggerDoc{
+		paths:       map[string]spec.PathItem{},
+		definitions: spec.Definitions{},
+	}
+
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	nullBoolType  = reflect.TypeOf(NullBool{})
+	jsonType      = reflect.TypeOf(JSON{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func init() {
+	swag.Register(SwaggerInstanceName, swaggerDocs)
+}
+
+// swaggerDoc keeps the operations added at runtime
+type swaggerDoc struct {
+	mu          sync.RWMutex
+	paths       map[string]spec.PathItem
+	definitions spec.Definitions
+}
+
+// ReadDoc implements swag.Swagger
+func (d *swaggerDoc) ReadDoc() string {
+	doc := &spec.Swagger{
+		SwaggerProps: spec.SwaggerProps{
+			Swagger: "2.0",
+			Info:    &spec.Info{InfoProps: spec.InfoProps{Title: "API"}},
+		},
+	}
+
+	if base := swag.GetSwagger(swag.Name); base != nil {
+		_ = json.Unmarshal([]byte(base.ReadDoc()), doc)
+	}
+
+	if doc.Paths == nil {
+		doc.Paths = &spec.Paths{}
+	}
+	if doc.Paths.Paths == nil {
+		doc.Paths.Paths = map[string]spec.PathItem{}
+	}
+	if doc.Definitions == nil {
+		doc.Definitions = spec.Definitions{}
+	}
+
+	d.mu.RLock()
+	for p, item := range d.paths {
+		p = strings.TrimPrefix(p, strings.TrimSuffix(doc.BasePath, "/"))
+		if _, exists := doc.Paths.Paths[p]; !exists {
+			doc.Paths.Paths[p] = item
+		}
+	}
+	for name, def := range d.definitions {
+		if _, exists := doc.Definitions[name]; !exists {
+			doc.Definitions[name] = def
+		}
+	}
+	d.mu.RUnlock()
+
+	b, _ := json.Marshal(doc)
+	return string(b)
+}
+
+// AddSwaggerOperation documents an operation of an echo route path (like /api/v1/users/:id)
+func AddSwaggerOperation(method, path string, op *spec.Operation) {
+	swaggerDocs.mu.Lock()
+	defer swaggerDocs.mu.Unlock()
+
+	path = swaggerPath(path)
+	item := swaggerDocs.paths[path]
+
+	switch strings.ToUpper(method) {
+	case "GET":
+		item.Get = op
+	case "POST":
+		item.Post = op
+	case "PUT":
+		item.Put = op
+	case "PATCH":
+		item.Patch = op
+	case "DELETE":
+		item.Delete = op
+	case "HEAD":
+		item.Head = op
+	case "OPTIONS":
+		item.Options = op
+	}
+
+	swaggerDocs.paths[path] = item
+}
+
+// AddSwaggerDefinition adds the schema of t to swagger definitions
+// and returns a reference to it
+func AddSwaggerDefinition(t reflect.Type) *spec.Schema {
+	swaggerDocs.mu.Lock()
+	defer swaggerDocs.mu.Unlock()
+
+	return swaggerSchemaOf(t, swaggerDocs.definitions)
+}
+
+// swaggerPath converts echo path params to swagger params, /users/:id -> /users/{id}
+func swaggerPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") {
+			parts[i] = "{" + strings.TrimPrefix(p, ":") + "}"
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// swaggerSchemaOf builds the schema of t using json tags,
+// structs are added to defs and referenced
+func swaggerSchemaOf(t reflect.Type, defs spec.Definitions) *spec.Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType, deletedAtType:
+		return spec.DateTimeProperty()
+	case nullBoolType:
+		return spec.BoolProperty()
+	case jsonType:
+		return &spec.Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return spec.BoolProperty()
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return spec.Int32Property()
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return spec.Int64Property()
+	case reflect.Float32:
+		return spec.Float32Property()
+	case reflect.Float64:
+		return spec.Float64Property()
+	case reflect.String:
+		return spec.StringProperty()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return spec.StrFmtProperty("byte")
+		}
+		return spec.ArrayProperty(swaggerSchemaOf(t.Elem(), defs))
+	case reflect.Map:
+		return spec.MapProperty(swaggerSchemaOf(t.Elem(), defs))
+	case reflect.Struct:
+		if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) || t.Name() == "" {
+			return &spec.Schema{}
+		}
+
+		name := t.Name()
+		if _, exists := defs[name]; !exists {
+			// placeholder prevents infinite recursion on self referencing structs
+			defs[name] = spec.Schema{}
+			schema := spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"object"}, Properties: map[string]spec.Schema{}}}
+			addSwaggerProperties(t, &schema, defs)
+			defs[name] = schema
+		}
+
+		return spec.RefSchema("#/definitions/" + name)
+	}
+
+	return &spec.Schema{}
+}
+
+// addSwaggerProperties adds exported fields of t and its embedded structs to schema properties
+func addSwaggerProperties(t reflect.Type, schema *spec.Schema, defs spec.Definitions) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addSwaggerProperties(ft, schema, defs)
+				continue
+			}
+		}
+
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		schema.Properties[name] = *swaggerSchemaOf(f.Type, defs)
+	}
+}
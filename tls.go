@@ -0,0 +1,245 @@
+package simutils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTLSCertificate = errors.New("tls certificate and key files are required")
+	ErrTLSClientCA    = errors.New("no certificate is found in client ca file")
+	ErrTLSVersion     = errors.New("unsupported tls version")
+	ErrTLSCipherSuite = errors.New("unsupported tls cipher suite")
+	ErrTLSClientAuth  = errors.New("unsupported tls client auth")
+)
+
+// DefaultTLSReloadInterval is the interval of checking certificate files when ReloadInterval is not set
+var DefaultTLSReloadInterval = time.Minute
+
+type (
+	// TLSConfig is the tls config of HttpServerConfig, like
+	//
+	//	{"cert_file": "server.crt", "key_file": "server.key", "client_ca_file": "ca.crt", "min_version": "1.3"}
+	TLSConfig struct {
+		CertFile string `json:"cert_file"`
+		KeyFile  string `json:"key_file"`
+		// ClientCAFile is a pem bundle of CAs which sign client certificates,
+		// client certificates are required when it is set
+		ClientCAFile string `json:"client_ca_file,omitempty"`
+		// ClientAuth is one of request, require, verify_if_given and require_and_verify,
+		// it is require_and_verify by default when ClientCAFile is set
+		ClientAuth string `json:"client_auth,omitempty"`
+		// MinVersion is one of 1.0, 1.1, 1.2 and 1.3, it is 1.2 by default
+		MinVersion string `json:"min_version,omitempty"`
+		// CipherSuites are names of cipher suites like TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		// go defaults are used if it is empty. TLS 1.3 cipher suites are not configurable.
+		CipherSuites []string `json:"cipher_suites,omitempty"`
+		// DisableHTTP2 serves only HTTP/1.1
+		DisableHTTP2 bool `json:"disable_http2,omitempty"`
+		// ReloadInterval is the minimum interval of checking certificate files for changes
+		ReloadInterval Duration `json:"reload_interval,omitempty"`
+	}
+
+	// certReloader loads the certificate again when its files are modified,
+	// new connections use the new certificate and open connections are not dropped
+	certReloader struct {
+		certFile, keyFile string
+		interval          time.Duration
+
+		mu        sync.RWMutex
+		cert      *tls.Certificate
+		modTime   time.Time
+		checkedAt time.Time
+	}
+)
+
+var (
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	tlsClientAuths = map[string]tls.ClientAuthType{
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify_if_given":    tls.VerifyClientCertIfGiven,
+		"require_and_verify": tls.RequireAndVerifyClientCert,
+	}
+)
+
+// Build returns the tls.Config of conf, the certificate is reloaded when its files change
+func (conf *TLSConfig) Build() (*tls.Config, error) {
+	if conf.CertFile == "" || conf.KeyFile == "" {
+		return nil, ErrTLSCertificate
+	}
+
+	reloader, err := newCertReloader(conf.CertFile, conf.KeyFile, conf.ReloadInterval.Duration)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConf := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	if conf.DisableHTTP2 {
+		tlsConf.NextProtos = []string{"http/1.1"}
+	}
+
+	if conf.MinVersion != "" {
+		v, ok := tlsVersions[conf.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTLSVersion, conf.MinVersion)
+		}
+		tlsConf.MinVersion = v
+	}
+
+	if len(conf.CipherSuites) > 0 {
+		if tlsConf.CipherSuites, err = cipherSuitesOf(conf.CipherSuites); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.ClientCAFile != "" {
+		pem, err := os.ReadFile(conf.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConf.ClientCAs = x509.NewCertPool()
+		if !tlsConf.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: %s", ErrTLSClientCA, conf.ClientCAFile)
+		}
+		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if conf.ClientAuth != "" {
+		auth, ok := tlsClientAuths[conf.ClientAuth]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTLSClientAuth, conf.ClientAuth)
+		}
+		tlsConf.ClientAuth = auth
+	}
+
+	return tlsConf, nil
+}
+
+// configureServer sets the tls config of s, HTTP/2 is disabled by an empty TLSNextProto
+func (conf *TLSConfig) configureServer(s *http.Server) error {
+	tlsConf, err := conf.Build()
+	if err != nil {
+		return err
+	}
+
+	s.TLSConfig = tlsConf
+	if conf.DisableHTTP2 {
+		s.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+
+	return nil
+}
+
+func cipherSuitesOf(names []string) ([]uint16, error) {
+	suites := map[string]uint16{}
+	for _, s := range tls.CipherSuites() {
+		suites[s.Name] = s.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTLSCipherSuite, name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	if interval <= 0 {
+		interval = DefaultTLSReloadInterval
+	}
+
+	r := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate, the files are checked at most once per interval
+// and the last loaded certificate is kept if the new files are invalid
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	cert, checkedAt := r.cert, r.checkedAt
+	r.mu.RUnlock()
+
+	if time.Since(checkedAt) < r.interval {
+		return cert, nil
+	}
+
+	if modTime, err := r.filesModTime(); err == nil && !modTime.Equal(r.loadedModTime()) {
+		_ = r.reload()
+	}
+
+	r.mu.Lock()
+	r.checkedAt = time.Now()
+	cert = r.cert
+	r.mu.Unlock()
+
+	return cert, nil
+}
+
+func (r *certReloader) reload() error {
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.cert, r.modTime, r.checkedAt = &cert, modTime, time.Now()
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *certReloader) loadedModTime() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.modTime
+}
+
+// filesModTime returns the latest modification time of the certificate and key files
+func (r *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
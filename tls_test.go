@@ -0,0 +1,234 @@
+package simutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// tlsTestCert creates a certificate of name signed by parent, a self-signed CA if parent is nil
+func tlsTestCert(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := tmpl, any(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, _ := x509.ParseCertificate(der)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeTLSTestCert writes the pem files of cert into dir
+func writeTLSTestCert(t *testing.T, dir, name string, cert tls.Certificate) (certFile, keyFile string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile
+}
+
+func TestHttpServer_RunTLS(t *testing.T) {
+	var (
+		dir               = t.TempDir()
+		ca                = tlsTestCert(t, "ca", nil)
+		caFile, _         = writeTLSTestCert(t, dir, "ca", ca)
+		certFile, keyFile = writeTLSTestCert(t, dir, "server", tlsTestCert(t, "server", &ca))
+		client            = tlsTestCert(t, "client", &ca)
+		roots             = x509.NewCertPool()
+		h                 = &HttpServer{}
+		errCh             = make(chan error, 1)
+		serverCommonName  = func(resp *http.Response) string { return resp.TLS.PeerCertificates[0].Subject.CommonName }
+		newClient         = func(certs ...tls.Certificate) *http.Client {
+			return &http.Client{Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+				ForceAttemptHTTP2: true,
+				DisableKeepAlives: true,
+			}}
+		}
+	)
+	roots.AddCert(ca.Leaf)
+
+	h.Address = freeTestAddress(t)
+	h.TLS = &TLSConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ClientCAFile:   caFile,
+		ReloadInterval: Duration{time.Nanosecond},
+	}
+	if err := h.newEcho(); err != nil {
+		t.Fatal(err)
+	}
+	h.echo.HidePort = true
+
+	go func() { errCh <- h.Run() }()
+	defer func() {
+		h.echo.Close()
+		if err := <-errCh; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}()
+
+	url := "https://" + h.Address + "/healthinfo"
+	waitHttpServer(t, newClient(client), url)
+
+	t.Run("mtls and http2", func(t *testing.T) {
+		resp, err := newClient(client).Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+			t.Errorf("status = %d, proto = %s, want 200 HTTP/2", resp.StatusCode, resp.Proto)
+		}
+		if cn := serverCommonName(resp); cn != "server" {
+			t.Errorf("certificate = %s, want server", cn)
+		}
+	})
+
+	t.Run("no client certificate", func(t *testing.T) {
+		if resp, err := newClient().Get(url); err == nil {
+			resp.Body.Close()
+			t.Errorf("Get() error = nil, want certificate required")
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		writeTLSTestCert(t, dir, "server", tlsTestCert(t, "renewed", &ca))
+		future := time.Now().Add(time.Minute)
+		for _, name := range []string{certFile, keyFile} {
+			if err := os.Chtimes(name, future, future); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		resp, err := newClient(client).Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if cn := serverCommonName(resp); cn != "renewed" {
+			t.Errorf("certificate = %s, want renewed", cn)
+		}
+	})
+}
+
+func TestTLSConfig_Build(t *testing.T) {
+	var (
+		dir               = t.TempDir()
+		certFile, keyFile = writeTLSTestCert(t, dir, "server", tlsTestCert(t, "server", nil))
+	)
+
+	tests := []struct {
+		name    string
+		conf    TLSConfig
+		check   func(*tls.Config) bool
+		wantErr error
+	}{
+		{
+			name:  "defaults",
+			conf:  TLSConfig{CertFile: certFile, KeyFile: keyFile},
+			check: func(c *tls.Config) bool { return c.MinVersion == tls.VersionTLS12 && c.NextProtos[0] == "h2" },
+		},
+		{
+			name: "options",
+			conf: TLSConfig{
+				CertFile:     certFile,
+				KeyFile:      keyFile,
+				MinVersion:   "1.3",
+				CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+				ClientAuth:   "verify_if_given",
+				DisableHTTP2: true,
+			},
+			check: func(c *tls.Config) bool {
+				return c.MinVersion == tls.VersionTLS13 &&
+					c.CipherSuites[0] == tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 &&
+					c.ClientAuth == tls.VerifyClientCertIfGiven &&
+					len(c.NextProtos) == 1 && c.NextProtos[0] == "http/1.1"
+			},
+		},
+		{
+			name:    "no certificate",
+			conf:    TLSConfig{},
+			wantErr: ErrTLSCertificate,
+		},
+		{
+			name:    "invalid version",
+			conf:    TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "2.0"},
+			wantErr: ErrTLSVersion,
+		},
+		{
+			name:    "invalid cipher suite",
+			conf:    TLSConfig{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{"NULL"}},
+			wantErr: ErrTLSCipherSuite,
+		},
+		{
+			name:    "invalid client ca",
+			conf:    TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile},
+			wantErr: ErrTLSClientCA,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.conf.Build()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil && !tt.check(got) {
+				t.Errorf("Build() = %+v", got)
+			}
+		})
+	}
+}
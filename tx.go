@@ -0,0 +1,197 @@
+package simutils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var (
+	// TxMaxRetries is the number of times WithTx retries a transaction
+	// which failed by a serialization failure or deadlock
+	TxMaxRetries = 3
+	// TxRetryBackoff is the delay before the first retry, it is doubled on every retry
+	TxRetryBackoff = 50 * time.Millisecond
+
+	// errTxRollback rolls back the transaction of TxMiddleware
+	errTxRollback = errors.New("transaction is rolled back")
+)
+
+// txKey is the context key of transaction
+type txKey struct{}
+
+// WithTx runs fn in a transaction which is stored in the context passed to fn,
+// so nested WithTx calls and repositories use the same transaction.
+// Nested calls run in a savepoint and are rolled back alone if fn returns an error.
+// The outermost transaction is retried on serialization failures and deadlocks.
+func WithTx(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	run := func(db *gorm.DB) error {
+		// gorm uses savepoints when db is a transaction
+		return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(ContextWithTx(ctx, tx), tx)
+		})
+	}
+
+	if tx, ok := TxFromContext(ctx); ok {
+		return run(tx)
+	}
+
+	backoff := TxRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := run(db)
+		if err == nil || attempt >= TxMaxRetries || !IsRetryableTxError(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff + time.Duration(rand.Int63n(int64(backoff)+1))):
+			backoff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ContextWithTx returns a context which carries tx
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction of ctx
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// DBFromContext returns the transaction of ctx or db if ctx has no transaction,
+// both bound to ctx
+func DBFromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
+
+// IsRetryableTxError reports whether err is a serialization failure or a deadlock:
+// SQLSTATE 40001 and 40P01 on postgres, error 1205 on sqlserver and 1213 on mysql
+func IsRetryableTxError(err error) bool {
+	var (
+		pgErr    interface{ SQLState() string }
+		mssqlErr interface{ SQLErrorNumber() int32 }
+		mysqlErr *mysql.MySQLError
+	)
+
+	switch {
+	case errors.As(err, &pgErr):
+		return pgErr.SQLState() == "40001" || pgErr.SQLState() == "40P01"
+	case errors.As(err, &mssqlErr):
+		return mssqlErr.SQLErrorNumber() == 1205
+	case errors.As(err, &mysqlErr):
+		return mysqlErr.Number == 1213
+	}
+
+	return false
+}
+
+// TxMiddleware runs the request in a transaction of db which is available by TxFromContext
+// on the request context. The transaction is rolled back if the handler returns an error
+// or responds with a status code of 400 or more. Requests are not retried.
+// The response is buffered and written after the transaction is committed,
+// so a failed commit is returned as the error of the request instead of the response
+// of the handler. Handlers must not flush or hijack the response.
+//
+//	e.POST("/orders", h.CreateOrder, simutils.TxMiddleware(db))
+func TxMiddleware(db *gorm.DB) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			var (
+				req        = ctx.Request()
+				res        = ctx.Response()
+				writer     = res.Writer
+				buf        = &txResponseWriter{header: writer.Header().Clone()}
+				handlerErr error
+			)
+
+			res.Writer = buf
+			defer func() { res.Writer = writer }()
+
+			err := db.WithContext(req.Context()).Transaction(func(tx *gorm.DB) error {
+				ctx.SetRequest(req.WithContext(ContextWithTx(req.Context(), tx)))
+				defer ctx.SetRequest(req)
+
+				if handlerErr = next(ctx); handlerErr != nil {
+					return handlerErr
+				}
+
+				if res.Status >= http.StatusBadRequest {
+					return errTxRollback
+				}
+
+				return nil
+			})
+
+			res.Writer = writer
+
+			if handlerErr == nil && err != nil && !errors.Is(err, errTxRollback) {
+				handlerErr = err
+			}
+			if handlerErr != nil {
+				// the buffered response is dropped and the error handler responds
+				res.Committed, res.Status, res.Size = false, http.StatusOK, 0
+				return handlerErr
+			}
+
+			return buf.writeTo(res)
+		}
+	}
+}
+
+// txResponseWriter buffers the response of TxMiddleware
+type txResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header implements http.ResponseWriter interface
+func (w *txResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter interface
+func (w *txResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// Write implements http.ResponseWriter interface
+func (w *txResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// writeTo writes the buffered response to the writer of res
+func (w *txResponseWriter) writeTo(res *echo.Response) error {
+	header := res.Writer.Header()
+	for k := range header {
+		delete(header, k)
+	}
+	for k, v := range w.header {
+		header[k] = v
+	}
+
+	if w.status == 0 {
+		return nil
+	}
+
+	res.Writer.WriteHeader(w.status)
+	_, err := res.Writer.Write(w.body.Bytes())
+
+	return err
+}
@@ -0,0 +1,164 @@
+package simutils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type txTestError string
+
+func (e txTestError) Error() string    { return "sqlstate " + string(e) }
+func (e txTestError) SQLState() string { return string(e) }
+
+func TestWithTx(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:tx_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	if err := db.AutoMigrate(&repositoryTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	TxRetryBackoff = time.Millisecond
+	repo := NewRepository[repositoryTestItem](db)
+
+	t.Run("nested savepoint", func(t *testing.T) {
+		err := WithTx(context.Background(), db, func(ctx context.Context, tx *gorm.DB) error {
+			if err := repo.Create(ctx, &repositoryTestItem{Code: "a"}); err != nil {
+				return err
+			}
+
+			nestedErr := WithTx(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+				if err := repo.Create(ctx, &repositoryTestItem{Code: "b"}); err != nil {
+					return err
+				}
+				return errors.New("rollback b")
+			})
+			if nestedErr == nil {
+				t.Errorf("WithTx() nested error = nil")
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var codes []string
+		db.Model(&repositoryTestItem{}).Order("code").Pluck("code", &codes)
+		if len(codes) != 1 || codes[0] != "a" {
+			t.Errorf("WithTx() codes = %v, want [a]", codes)
+		}
+	})
+
+	tests := []struct {
+		name         string
+		err          error
+		wantAttempts int
+	}{
+		{name: "retry serialization failure", err: txTestError("40001"), wantAttempts: TxMaxRetries + 1},
+		{name: "retry deadlock", err: txTestError("40P01"), wantAttempts: TxMaxRetries + 1},
+		{name: "no retry", err: txTestError("23505"), wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := WithTx(context.Background(), db, func(ctx context.Context, tx *gorm.DB) error {
+				attempts++
+				return tt.err
+			})
+			if !errors.Is(err, tt.err) || attempts != tt.wantAttempts {
+				t.Errorf("WithTx() error = %v, attempts = %d, want %d", err, attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestTxMiddleware(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:tx_middleware_test?mode=memory&cache=shared"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	if err := db.AutoMigrate(&repositoryTestItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRepository[repositoryTestItem](db)
+
+	e := echo.New()
+	e.POST("/items/:code", func(ctx echo.Context) error {
+		if err := repo.Create(ctx.Request().Context(), &repositoryTestItem{Code: ctx.Param("code")}); err != nil {
+			return err
+		}
+		if ctx.QueryParam("fail") != "" {
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+		return ctx.NoContent(http.StatusCreated)
+	}, TxMiddleware(db))
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantExists bool
+	}{
+		{name: "commit", path: "/items/a", wantStatus: http.StatusCreated, wantExists: true},
+		{name: "rollback on error status", path: "/items/b?fail=1", wantStatus: http.StatusBadRequest, wantExists: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+
+			var count int64
+			db.Model(&repositoryTestItem{}).Where("code = ?", tt.path[len("/items/"):len("/items/")+1]).Count(&count)
+			if rec.Code != tt.wantStatus || (count == 1) != tt.wantExists {
+				t.Errorf("TxMiddleware() status = %d, count = %d", rec.Code, count)
+			}
+		})
+	}
+}
+
+func TestTxMiddleware_CommitError(t *testing.T) {
+	dbConn := &DBConnection{DBConfig: DBConfig{Driver: SQLite, DSN: "file:tx_commit_test?mode=memory&cache=shared&_foreign_keys=1"}}
+	if err := Connect(dbConn); err != nil {
+		t.Fatal(err)
+	}
+	db := dbConn.DB
+	for _, sql := range []string{
+		"CREATE TABLE tx_parents (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE tx_children (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES tx_parents(id) DEFERRABLE INITIALLY DEFERRED)",
+	} {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := echo.New()
+	e.POST("/children", func(ctx echo.Context) error {
+		// the foreign key is checked on commit
+		if err := DBFromContext(ctx.Request().Context(), db).Exec("INSERT INTO tx_children (parent_id) VALUES (1)").Error; err != nil {
+			return err
+		}
+		ctx.Response().Header().Set("X-Child", "1")
+		return ctx.JSON(http.StatusCreated, map[string]int{"id": 1})
+	}, TxMiddleware(db))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/children", nil))
+
+	var count int64
+	db.Table("tx_children").Count(&count)
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("X-Child") != "" || count != 0 {
+		t.Errorf("TxMiddleware() status = %d, header = %q, count = %d", rec.Code, rec.Header().Get("X-Child"), count)
+	}
+}
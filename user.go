@@ -1,11 +1,19 @@
 package simutils
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrUserNoFound = errors.New("user/user id not found")
 )
 
+// UserProtectedFields are the fields of User which are never bound by RegisterResource of User,
+// like ResourceOptions[User]{Protected: UserProtectedFields}. Status is changed by UserService.SetStatus
+// which revokes the tokens of user.
+var UserProtectedFields = []string{"role", "status"}
+
 type Users []*User
 
 // User ...
@@ -18,6 +26,15 @@ type User struct {
 	Password  string   `json:"-"`
 	Email     string   `json:"email"`
 	Status    UserMode `json:"status" gorm:"default:1"`
+	// Role is the role of tokens of user, see Policy. It is read-only for clients,
+	// resources of User must protect it by UserProtectedFields and handlers must not bind request bodies into User.
+	Role string `json:"role,omitempty" gorm:"size:64"`
+	// FailedLogins is the number of failed logins since the last successful login or lockout
+	FailedLogins int `json:"-"`
+	// LockedUntil is the end of lockout after too many failed logins
+	LockedUntil *time.Time `json:"-"`
+	// TokenGeneration is increased to revoke all tokens of user, see UserGeneration
+	TokenGeneration int `json:"-" gorm:"not null;default:0"`
 }
 
 func (u *User) FullName() string {